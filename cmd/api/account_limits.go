@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+func (app *application) getAccountLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	limits, err := app.store.AccountLimit.Get(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"limits": limits}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAccountLimitsHandler replaces the limits of an account, a limit left out or set to
+// null is lifted.
+func (app *application) updateAccountLimitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var input struct {
+		MaxTransferAmount *int64 `json:"max_transfer_amount"`
+		MaxDailyAmount    *int64 `json:"max_daily_amount"`
+		MaxDailyCount     *int   `json:"max_daily_count"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	acc, ok := app.getAccessibleAccount(w, r, id)
+	if !ok {
+		return
+	}
+	if acc.Status == models.AccountStatusClosed {
+		app.conflictResponse(w, r, store.ErrAccountClosed)
+		return
+	}
+
+	limits := &models.AccountLimits{
+		AccountID:         acc.ID,
+		MaxTransferAmount: input.MaxTransferAmount,
+		MaxDailyAmount:    input.MaxDailyAmount,
+		MaxDailyCount:     input.MaxDailyCount,
+	}
+	v := validator.New()
+	if models.ValidateAccountLimits(v, limits); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = app.store.AccountLimit.Put(r.Context(), limits)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"limits": limits}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
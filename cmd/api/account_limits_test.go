@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_getAccountLimitsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		owner      int64
+		statusCode int
+	}{
+		{"own account", testUser.ID, http.StatusOK},
+		{"other users account", testAdmin.ID, http.StatusNotFound},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_getAccountLimits := mock.GetAccountLimits
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetAccountLimits = _getAccountLimits
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: e.owner, Status: models.AccountStatusActive}, nil
+				}
+				mock.GetAccountLimits = func(accountID int64) (*models.AccountLimits, error) {
+					maxCount := 5
+					return &models.AccountLimits{AccountID: accountID, MaxDailyCount: &maxCount}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/limits", nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.getAccountLimitsHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_updateAccountLimitsHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		status     string
+		statusCode int
+	}{
+		{"success", `{"max_transfer_amount": 1000, "max_daily_amount": 5000}`, models.AccountStatusActive, http.StatusOK},
+		{"lift all limits", `{}`, models.AccountStatusActive, http.StatusOK},
+		{"not positive", `{"max_daily_count": 0}`, models.AccountStatusActive, http.StatusUnprocessableEntity},
+		{"transfer above daily", `{"max_transfer_amount": 6000, "max_daily_amount": 5000}`, models.AccountStatusActive, http.StatusUnprocessableEntity},
+		{"closed account", `{"max_daily_count": 3}`, models.AccountStatusClosed, http.StatusConflict},
+		{"badly formed json", `{"max_daily_count": "3"}`, models.AccountStatusActive, http.StatusBadRequest},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_putAccountLimits := mock.PutAccountLimits
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.PutAccountLimits = _putAccountLimits
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			var stored *models.AccountLimits
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID, Status: e.status}, nil
+				}
+				mock.PutAccountLimits = func(l *models.AccountLimits) error {
+					stored = l
+					return nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/1/limits", strings.NewReader(e.body))
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.updateAccountLimitsHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if e.statusCode != http.StatusOK {
+				if stored != nil {
+					t.Error("expected the limits not to be stored")
+				}
+				return
+			}
+			var body struct {
+				Limits map[string]any `json:"limits"`
+			}
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range []string{"max_transfer_amount", "max_daily_amount", "max_daily_count"} {
+				if _, ok := body.Limits[key]; !ok {
+					t.Errorf("expected %s in the response, null when lifted", key)
+				}
+			}
+		})
+	}
+}
+
+func Test_application_updateAccountLimitsHandler_not_permitted(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/1/limits", strings.NewReader(`{}`))
+	req = authenticated(req)
+	handler := app.requirePermission(models.PermissionAccountsLimits, app.updateAccountLimitsHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected status code: %d, but got %d", http.StatusForbidden, response.Result().StatusCode)
+	}
+}
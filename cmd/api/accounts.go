@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
 )
 
 func (app *application) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
@@ -20,11 +24,23 @@ func (app *application) CreateAccountHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	acc := &models.Account{
+		UserID:   app.contextGetUser(r).ID,
 		Owner:    input.Owner,
 		Balance:  input.Balance,
 		Currency: input.Currency,
 	}
-	err = app.store.Account.Create(acc)
+	v := validator.New()
+	v.Check(acc.Balance >= 0, "balance", "must not be negative")
+	if models.ValidateAccount(v, acc); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// an opening balance creates money, accounts are otherwise funded through deposits
+	if acc.Balance != 0 && !app.contextGetUser(r).HasPermission(models.PermissionAccountsOpeningBalance) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	err = app.store.Account.Create(r.Context(), acc)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -38,25 +54,53 @@ func (app *application) getAccountByIDHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	acc, err := app.store.Account.Get(id)
+	includeClosed, err := app.readBool(r.URL.Query(), "include_closed")
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	acc, ok := app.getAccessibleAccount(w, r, id)
+	if !ok {
+		return
+	}
+	if acc.Status == models.AccountStatusClosed && !includeClosed {
+		app.notFoundRespose(w, r)
+		return
+	}
+	app.writeJSON(w, envelope{"account": acc}, http.StatusOK, nil)
+}
+
+// getAccessibleAccount fetches the account if the authenticated user may operate on it, other
+// users' accounts are reported as not found to customers. The error response has already been
+// written when ok is false.
+func (app *application) getAccessibleAccount(w http.ResponseWriter, r *http.Request, id int64) (acc *models.Account, ok bool) {
+	acc, err := app.store.Account.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrRecordNotFound):
 			app.notFoundRespose(w, r)
-			return
+			return nil, false
 		default:
 			app.serverErrorResponse(w, r, err)
-			return
+			return nil, false
 		}
 	}
-	app.writeJSON(w, envelope{"account": acc}, http.StatusOK, nil)
+	if !canAccessAccount(app.contextGetUser(r), acc) {
+		app.notFoundRespose(w, r)
+		return nil, false
+	}
+	return acc, true
+}
+
+// canAccessAccount reports whether user owns acc or may operate on every account.
+func canAccessAccount(user *models.User, acc *models.Account) bool {
+	return acc.UserID == user.ID || user.HasPermission(models.PermissionAccountsAccessAll)
 }
 
 func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		ID       int64  `json:"id"`
 		Owner    string `json:"owner"`
-		Balance  int64  `json:"balance"`
 		Currency string `json:"currency"`
 	}
 	err := app.readJSON(w, r, &input)
@@ -64,13 +108,26 @@ func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Requ
 		app.badRequestErrorResponse(w, r, err)
 		return
 	}
-	acc := &models.Account{
-		ID:       input.ID,
-		Owner:    input.Owner,
-		Currency: input.Currency,
-		Balance:  input.Balance,
+	acc, ok := app.getAccessibleAccount(w, r, input.ID)
+	if !ok {
+		return
+	}
+	if acc.Status == models.AccountStatusClosed {
+		app.conflictResponse(w, r, store.ErrAccountClosed)
+		return
+	}
+	if input.Currency != acc.Currency && !app.contextGetUser(r).HasPermission(models.PermissionAccountsCurrencyChange) {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	acc.Owner = input.Owner
+	acc.Currency = input.Currency
+	v := validator.New()
+	if models.ValidateAccount(v, acc); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
 	}
-	err = app.store.Account.UpdateAccount(acc)
+	err = app.store.Account.UpdateAccount(r.Context(), acc)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrRecordNotFound):
@@ -89,63 +146,141 @@ func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Requ
 	}
 }
 
-func (app *application) updateBalanceHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := app.parseReqParam(r, "id")
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
+func (app *application) listAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	filter := models.AccountFilter{
+		Owner:    qs.Get("owner"),
+		Currency: qs.Get("currency"),
+		Sort:     qs.Get("sort"),
+		After:    qs.Get("after"),
+	}
+	var err error
+	if filter.IncludeClosed, err = app.readBool(qs, "include_closed"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
 		return
 	}
-	var input struct {
-		Balance int64 `json:"balance"`
+	// customers only ever see their own accounts, tellers and admins may list everyone's
+	// accounts or narrow the listing down to a single user
+	user := app.contextGetUser(r)
+	if user.HasPermission(models.PermissionAccountsAccessAll) {
+		if filter.UserID, err = app.readInt64(qs, "user_id"); err != nil {
+			app.badRequestErrorResponse(w, r, err)
+			return
+		}
+	} else {
+		filter.UserID = user.ID
 	}
-	err = app.readJSON(w, r, &input)
-	if err != nil {
+	if filter.Sort == "" {
+		filter.Sort = "id"
+	}
+	if !validator.PermittedValue(filter.Sort, models.AccountSortSafelist...) {
+		app.badRequestErrorResponse(w, r, fmt.Errorf("sort must be one of %s", strings.Join(models.AccountSortSafelist, ", ")))
+		return
+	}
+	if filter.Limit, err = app.readInt(qs, "limit", 20); err != nil {
 		app.badRequestErrorResponse(w, r, err)
 		return
 	}
-	acc, err := app.store.Account.Get(id)
+	if filter.Limit < 1 || filter.Limit > 100 {
+		app.badRequestErrorResponse(w, r, errors.New("limit must be between 1 and 100"))
+		return
+	}
+
+	acc, metadata, err := app.store.Account.List(r.Context(), filter)
 	if err != nil {
 		switch {
-		case errors.Is(err, store.ErrRecordNotFound):
-			app.notFoundRespose(w, r)
+		case errors.Is(err, store.ErrInvalidCursor):
+			app.badRequestErrorResponse(w, r, err)
 			return
 		default:
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
-	acc.Balance = input.Balance
-	err = app.store.Account.UpdateBalance(acc)
+	err = app.writeJSON(w, envelope{"accounts": acc, "metadata": metadata}, http.StatusOK, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeJSON(w, envelope{"account": acc}, http.StatusOK, nil)
-	if err != nil {
-		app.serverErrorResponse(w, r, err)
-	}
 }
 
-func (app *application) listAccountsHandler(w http.ResponseWriter, r *http.Request) {
-	acc, err := app.store.Account.List()
+func (app *application) freezeAccountHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeAccountStatus(w, r, app.store.Account.Freeze)
+}
+
+func (app *application) unfreezeAccountHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeAccountStatus(w, r, app.store.Account.Unfreeze)
+}
+
+// closeAccountHandler closes the account, accounts are never deleted so that their entries and
+// transfers stay around.
+func (app *application) closeAccountHandler(w http.ResponseWriter, r *http.Request) {
+	app.changeAccountStatus(w, r, app.store.Account.Close)
+}
+
+// changeAccountStatus applies change to the account in the request path.
+func (app *application) changeAccountStatus(w http.ResponseWriter, r *http.Request, change func(context.Context, int64) (*models.Account, error)) {
+	id, err := app.parseReqParam(r, "id")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.writeJSON(w, envelope{"accounts": acc}, http.StatusOK, nil)
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	acc, err := change(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		case errors.Is(err, store.ErrInvalidStatusTransition), errors.Is(err, store.ErrNonZeroBalance):
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"account": acc}, http.StatusOK, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
-		return
 	}
 }
 
-func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+// setOverdraftLimitHandler changes how far below zero the balance of an account may go.
+func (app *application) setOverdraftLimitHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := app.parseReqParam(r, "id")
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	err = app.store.Account.Delete(id)
+	var input struct {
+		OverdraftLimit *int64 `json:"overdraft_limit"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.OverdraftLimit != nil, "overdraft_limit", "must be provided")
+	if input.OverdraftLimit != nil {
+		models.ValidateOverdraftLimit(v, *input.OverdraftLimit)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	acc, ok := app.getAccessibleAccount(w, r, id)
+	if !ok {
+		return
+	}
+	if acc.Status == models.AccountStatusClosed {
+		app.conflictResponse(w, r, store.ErrAccountClosed)
+		return
+	}
+	acc, err = app.store.Account.SetOverdraftLimit(r.Context(), id, *input.OverdraftLimit)
 	if err != nil {
 		switch {
 		case errors.Is(err, store.ErrRecordNotFound):
@@ -156,7 +291,13 @@ func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Requ
 			return
 		}
 	}
-	err = app.writeJSON(w, envelope{"message": "account successfully deleted"}, http.StatusOK, nil)
+	app.logger.PrintInfo("overdraft limit changed", map[string]any{
+		"account_id":      acc.ID,
+		"overdraft_limit": acc.OverdraftLimit,
+		"actor_id":        app.contextGetUser(r).ID,
+		"request_id":      app.contextGetRequestID(r),
+	})
+	err = app.writeJSON(w, envelope{"account": acc}, http.StatusOK, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
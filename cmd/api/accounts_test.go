@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -18,18 +19,28 @@ import (
 )
 
 var app = application{
-	store:  store.NewMockStore(),
-	logger: logger.New(os.Stdout, logger.LevelError),
+	store:   store.NewMockStore(),
+	logger:  logger.New(os.Stdout, logger.LevelError),
+	metrics: newMetrics(),
+}
+
+var testUser = &models.User{ID: 1, Name: "Ruthvik", Email: "ruthvik@example.com", Role: models.RoleCustomer}
+
+var testAdmin = &models.User{ID: 2, Name: "Admin", Email: "admin@example.com", Role: models.RoleAdmin}
+
+// authenticated returns req as sent by testUser.
+func authenticated(req *http.Request) *http.Request {
+	return app.contextSetUser(req, testUser)
 }
 
 func Test_application_createAccountHandler_success(t *testing.T) {
 	reqBody := `{
 					"owner": "Ruthvik",
-					"balance": 20000,
 					"Currency": "USD"
 				}`
 	handler := http.HandlerFunc(app.CreateAccountHandler)
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
 	response := httptest.NewRecorder()
 	handler.ServeHTTP(response, req)
 	if response.Result().StatusCode != http.StatusCreated {
@@ -41,10 +52,10 @@ func Test_application_createAccountHandler_database_error(t *testing.T) {
 	handler := http.HandlerFunc(app.CreateAccountHandler)
 	reqBody := `{
 					"owner": "Ruthvik",
-					"balance": 20000,
 					"Currency": "USD"
 				}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
 	_createAccount := mock.CreateAccount
 	defer func() {
 		mock.CreateAccount = _createAccount
@@ -69,6 +80,7 @@ func Test_application_createAccountHandler_badly_formed_json(t *testing.T) {
 					"Currency": "USD"
 				}`
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
 
 	handler := http.HandlerFunc(app.CreateAccountHandler)
 	response := httptest.NewRecorder()
@@ -78,8 +90,83 @@ func Test_application_createAccountHandler_badly_formed_json(t *testing.T) {
 	}
 }
 
+func Test_application_createAccountHandler_failed_validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		reqBody string
+		field   string
+	}{
+		{"empty owner", `{"owner": " ", "currency": "USD"}`, "owner"},
+		{"negative balance", `{"owner": "Ruthvik", "balance": -100, "currency": "USD"}`, "balance"},
+		{"unknown currency", `{"owner": "Ruthvik", "currency": "XYZ"}`, "currency"},
+		{"missing currency", `{"owner": "Ruthvik"}`, "currency"},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/", strings.NewReader(e.reqBody))
+			req = authenticated(req)
+			handler := http.HandlerFunc(app.CreateAccountHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+			}
+			var body struct {
+				Error map[string]string `json:"error"`
+			}
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.Error[e.field] == "" {
+				t.Errorf("expected an error for %s, but got %v", e.field, body.Error)
+			}
+		})
+	}
+}
+
+func Test_application_createAccountHandler_opening_balance(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       *models.User
+		statusCode int
+	}{
+		{"customer", testUser, http.StatusForbidden},
+		{"admin", testAdmin, http.StatusCreated},
+	}
+	_createAccount := mock.CreateAccount
+	defer func() {
+		mock.CreateAccount = _createAccount
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			created := false
+			{
+				// mock db calls
+				mock.CreateAccount = func(acc *models.Account) error {
+					created = true
+					return nil
+				}
+			}
+			reqBody := `{"owner": "Ruthvik", "balance": 20000, "currency": "USD"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/", strings.NewReader(reqBody))
+			req = app.contextSetUser(req, e.user)
+			handler := http.HandlerFunc(app.CreateAccountHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if created != (e.statusCode == http.StatusCreated) {
+				t.Errorf("expected the account to be created: %t", e.statusCode == http.StatusCreated)
+			}
+		})
+	}
+}
+
 func Test_application_getAccountByIDHandler_success(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
@@ -92,6 +179,7 @@ func Test_application_getAccountByIDHandler_success(t *testing.T) {
 		mock.GetAccountByID = func(id int64) (*models.Account, error) {
 			toReturn := &models.Account{
 				ID:        1,
+				UserID:    testUser.ID,
 				Owner:     "Ruthvik",
 				Balance:   4000,
 				Currency:  "USD",
@@ -110,6 +198,7 @@ func Test_application_getAccountByIDHandler_success(t *testing.T) {
 
 func Test_application_getAccountByIDHandler_no_records_found(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "10")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
@@ -131,8 +220,33 @@ func Test_application_getAccountByIDHandler_no_records_found(t *testing.T) {
 	}
 }
 
+func Test_application_getAccountByIDHandler_other_users_account(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "2")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID + 1}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.getAccountByIDHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
 func Test_application_getAccountByIDHandler_database_error(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
@@ -159,17 +273,22 @@ func Test_application_updateAccountHandler_success(t *testing.T) {
 	{
 		"id":1,
 		"owner":"R",
-		"balance": 2000,
 		"currency":"EUR"
 	}
 	`
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
 	_updateAccount := mock.UpdateAccount
 	defer func() {
+		mock.GetAccountByID = _getAccountByID
 		mock.UpdateAccount = _updateAccount
 	}()
 	{
 		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "EUR"}, nil
+		}
 		mock.UpdateAccount = func(acc *models.Account) error {
 			return nil
 		}
@@ -183,22 +302,73 @@ func Test_application_updateAccountHandler_success(t *testing.T) {
 	}
 }
 
-func Test_application_updateAccountHandler_badly_input(t *testing.T) {
+func Test_application_updateAccountHandler_currency_change(t *testing.T) {
+	tests := []struct {
+		name               string
+		user               *models.User
+		expectedStatusCode int
+	}{
+		{"customer", testUser, http.StatusForbidden},
+		{"admin", testAdmin, http.StatusOK},
+	}
 	reqBody := `
 	{
 		"id":1,
 		"owner":"R",
-		"balance": "2000",
+		"currency":"USD"
+	}
+	`
+	_getAccountByID := mock.GetAccountByID
+	_updateAccount := mock.UpdateAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.UpdateAccount = _updateAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "EUR"}, nil
+		}
+		mock.UpdateAccount = func(acc *models.Account) error {
+			return nil
+		}
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+			req = app.contextSetUser(req, e.user)
+			handler := http.HandlerFunc(app.updateAccountHandler)
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("%s: expected status %d, but got %d", e.name, e.expectedStatusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_updateAccountHandler_badly_input(t *testing.T) {
+	reqBody := `
+	{
+		"id":"1",
+		"owner":"R",
 		"currency":"EUR"
 	}
 	`
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
 	_updateAccount := mock.UpdateAccount
 	defer func() {
+		mock.GetAccountByID = _getAccountByID
 		mock.UpdateAccount = _updateAccount
 	}()
 	{
 		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "EUR"}, nil
+		}
 		mock.UpdateAccount = func(acc *models.Account) error {
 			return nil
 		}
@@ -212,7 +382,7 @@ func Test_application_updateAccountHandler_badly_input(t *testing.T) {
 	}
 }
 
-func Test_application_updateAccountHandler_record_not_found(t *testing.T) {
+func Test_application_updateAccountHandler_balance_rejected(t *testing.T) {
 	reqBody := `
 	{
 		"id":1,
@@ -222,12 +392,37 @@ func Test_application_updateAccountHandler_record_not_found(t *testing.T) {
 	}
 	`
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = app.contextSetUser(req, testAdmin)
+	handler := http.HandlerFunc(app.updateAccountHandler)
+	response := httptest.NewRecorder()
+
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+}
+
+func Test_application_updateAccountHandler_record_not_found(t *testing.T) {
+	reqBody := `
+	{
+		"id":1,
+		"owner":"R",
+		"currency":"EUR"
+	}
+	`
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
 	_updateAccount := mock.UpdateAccount
 	defer func() {
+		mock.GetAccountByID = _getAccountByID
 		mock.UpdateAccount = _updateAccount
 	}()
 	{
 		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "EUR"}, nil
+		}
 		mock.UpdateAccount = func(acc *models.Account) error {
 			return store.ErrRecordNotFound
 		}
@@ -246,17 +441,22 @@ func Test_application_updateAccountHandler_database_error(t *testing.T) {
 	{
 		"id":1,
 		"owner":"R",
-		"balance": 2000,
 		"currency":"EUR"
 	}
 	`
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
 	_updateAccount := mock.UpdateAccount
 	defer func() {
+		mock.GetAccountByID = _getAccountByID
 		mock.UpdateAccount = _updateAccount
 	}()
 	{
 		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "EUR"}, nil
+		}
 		mock.UpdateAccount = func(acc *models.Account) error {
 			return errors.New("error")
 		}
@@ -270,38 +470,30 @@ func Test_application_updateAccountHandler_database_error(t *testing.T) {
 	}
 }
 
-func Test_application_updateBalanceHandler_success(t *testing.T) {
-	reqBody := `{
-		"balance": 5000
-	}`
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/accounts", strings.NewReader(reqBody))
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_getAccountByID := mock.GetAccountByID
-	_updateBalance := mock.UpdateBalance
+func Test_application_listAccountsHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
+	_listAccounts := mock.ListAccounts
 	defer func() {
-		mock.GetAccountByID = _getAccountByID
-		mock.UpdateBalance = _updateBalance
+		mock.ListAccounts = _listAccounts
 	}()
 	{
 		// mock calls to db
-		mock.GetAccountByID = func(id int64) (*models.Account, error) {
-			toReturn := &models.Account{
-				ID:        1,
-				Owner:     "Ruthvik",
-				Balance:   4000,
-				Currency:  "USD",
-				CreatedAt: time.Now(),
+		mock.ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+			toReturn := []*models.Account{
+				{
+					ID:        1,
+					UserID:    testUser.ID,
+					Owner:     "Ruthvik",
+					Balance:   4000,
+					Currency:  "USD",
+					CreatedAt: time.Now(),
+				},
 			}
-			return toReturn, nil
-		}
-
-		mock.UpdateBalance = func(acc *models.Account) error {
-			return nil
+			return toReturn, models.CursorMetadata{Limit: filter.Limit, TotalRecords: 1}, nil
 		}
 	}
-	handler := http.HandlerFunc(app.updateBalanceHandler)
+	handler := http.HandlerFunc(app.listAccountsHandler)
 	response := httptest.NewRecorder()
 
 	handler.ServeHTTP(response, req)
@@ -310,243 +502,442 @@ func Test_application_updateBalanceHandler_success(t *testing.T) {
 	}
 }
 
-func Test_application_updateBalanceHandler_bad_input(t *testing.T) {
-	reqBody := `{
-		"balance": "5000"
-	}`
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/accounts", strings.NewReader(reqBody))
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_getAccountByID := mock.GetAccountByID
-	_updateBalance := mock.UpdateBalance
+func Test_application_listAccountsHandler_database_error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
+	req = authenticated(req)
+	_listAccounts := mock.ListAccounts
 	defer func() {
-		mock.GetAccountByID = _getAccountByID
-		mock.UpdateBalance = _updateBalance
+		mock.ListAccounts = _listAccounts
 	}()
-	handler := http.HandlerFunc(app.updateBalanceHandler)
+	{
+		// mock calls to db
+		mock.ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+			return nil, models.CursorMetadata{}, errors.New("error")
+		}
+	}
+	handler := http.HandlerFunc(app.listAccountsHandler)
 	response := httptest.NewRecorder()
 
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusBadRequest {
-		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
 	}
 }
 
-func Test_application_updateBalanceHandler_record_not_found(t *testing.T) {
-	reqBody := `{
-		"balance": 5000
-	}`
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/accounts", strings.NewReader(reqBody))
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_getAccountByID := mock.GetAccountByID
-	_updateBalance := mock.UpdateBalance
+func Test_application_listAccountsHandler_filters(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/?owner=Ruthvik&currency=USD&sort=-balance&after=abc&limit=5", nil)
+	req = authenticated(req)
+	_listAccounts := mock.ListAccounts
 	defer func() {
-		mock.GetAccountByID = _getAccountByID
-		mock.UpdateBalance = _updateBalance
+		mock.ListAccounts = _listAccounts
 	}()
 	{
 		// mock calls to db
-		mock.GetAccountByID = func(id int64) (*models.Account, error) {
-			return nil, store.ErrRecordNotFound
+		mock.ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+			expected := models.AccountFilter{UserID: testUser.ID, Owner: "Ruthvik", Currency: "USD", Sort: "-balance", After: "abc", Limit: 5}
+			if filter != expected {
+				t.Errorf("expected filter %+v, but got %+v", expected, filter)
+			}
+			return []*models.Account{}, models.CursorMetadata{Limit: filter.Limit}, nil
 		}
-
 	}
-	handler := http.HandlerFunc(app.updateBalanceHandler)
+	handler := http.HandlerFunc(app.listAccountsHandler)
 	response := httptest.NewRecorder()
 
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusNotFound {
-		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
 	}
 }
-func Test_application_updateBalanceHandler_fetch_account_database_error(t *testing.T) {
-	reqBody := `{
-		"balance": 5000
-	}`
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/accounts", strings.NewReader(reqBody))
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_getAccountByID := mock.GetAccountByID
-	_updateBalance := mock.UpdateBalance
+
+func Test_application_listAccountsHandler_all_users(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           *models.User
+		query          string
+		expectedUserID int64
+	}{
+		{"customer sees own accounts", testUser, "", testUser.ID},
+		{"customer can not list other users", testUser, "?user_id=5", testUser.ID},
+		{"admin sees every account", testAdmin, "", 0},
+		{"admin narrows down to a user", testAdmin, "?user_id=5", 5},
+	}
+	_listAccounts := mock.ListAccounts
 	defer func() {
-		mock.GetAccountByID = _getAccountByID
-		mock.UpdateBalance = _updateBalance
+		mock.ListAccounts = _listAccounts
 	}()
-	{
-		// mock calls to db
-		mock.GetAccountByID = func(id int64) (*models.Account, error) {
-			return nil, errors.New("error")
-		}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			{
+				// mock calls to db
+				mock.ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+					if filter.UserID != e.expectedUserID {
+						t.Errorf("expected user id %d, but got %d", e.expectedUserID, filter.UserID)
+					}
+					return []*models.Account{}, models.CursorMetadata{}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/"+e.query, nil)
+			req = app.contextSetUser(req, e.user)
+			handler := http.HandlerFunc(app.listAccountsHandler)
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusOK {
+				t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+			}
+		})
 	}
-	handler := http.HandlerFunc(app.updateBalanceHandler)
-	response := httptest.NewRecorder()
+}
 
-	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusInternalServerError {
-		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+func Test_application_listAccountsHandler_bad_input(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown sort", "sort=owner"},
+		{"non numeric limit", "limit=ten"},
+		{"limit out of range", "limit=500"},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/?"+e.query, nil)
+			req = authenticated(req)
+			handler := http.HandlerFunc(app.listAccountsHandler)
+			response := httptest.NewRecorder()
+
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+			}
+		})
 	}
 }
 
-func Test_application_updateBalanceHandler_update_balance_database_error(t *testing.T) {
-	reqBody := `{
-		"balance": 5000
-	}`
-	req := httptest.NewRequest(http.MethodPatch, "/api/v1/accounts", strings.NewReader(reqBody))
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "1")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_getAccountByID := mock.GetAccountByID
-	_updateBalance := mock.UpdateBalance
+func Test_application_listAccountsHandler_invalid_cursor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/?after=garbage", nil)
+	req = authenticated(req)
+	_listAccounts := mock.ListAccounts
 	defer func() {
-		mock.GetAccountByID = _getAccountByID
-		mock.UpdateBalance = _updateBalance
+		mock.ListAccounts = _listAccounts
 	}()
 	{
 		// mock calls to db
-		mock.GetAccountByID = func(id int64) (*models.Account, error) {
-			toReturn := &models.Account{
-				ID:        1,
-				Owner:     "Ruthvik",
-				Balance:   4000,
-				Currency:  "USD",
-				CreatedAt: time.Now(),
-			}
-			return toReturn, nil
-		}
-
-		mock.UpdateBalance = func(acc *models.Account) error {
-			return errors.New("error")
+		mock.ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+			return nil, models.CursorMetadata{}, store.ErrInvalidCursor
 		}
 	}
-	handler := http.HandlerFunc(app.updateBalanceHandler)
+	handler := http.HandlerFunc(app.listAccountsHandler)
 	response := httptest.NewRecorder()
 
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusInternalServerError {
-		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
 	}
 }
 
-func Test_application_listAccountsHandler_success(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
-	_listAccounts := mock.ListAccounts
+func Test_application_closeAccountHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_closeAccount := mock.CloseAccount
 	defer func() {
-		mock.ListAccounts = _listAccounts
+		mock.GetAccountByID = _getAccountByID
+		mock.CloseAccount = _closeAccount
 	}()
 	{
 		// mock calls to db
-		mock.ListAccounts = func() ([]*models.Account, error) {
-			toReturn := []*models.Account{
-				{
-					ID:        1,
-					Owner:     "Ruthvik",
-					Balance:   4000,
-					Currency:  "USD",
-					CreatedAt: time.Now(),
-				},
-			}
-			return toReturn, nil
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CloseAccount = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusClosed}, nil
 		}
 	}
-	handler := http.HandlerFunc(app.listAccountsHandler)
+	handler := http.HandlerFunc(app.closeAccountHandler)
 	response := httptest.NewRecorder()
-
 	handler.ServeHTTP(response, req)
 	if response.Result().StatusCode != http.StatusOK {
 		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
 	}
 }
 
-func Test_application_listAccountsHandler_database_error(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/", nil)
-	_listAccounts := mock.ListAccounts
+func Test_application_closeAccountHandler_database_error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_closeAccount := mock.CloseAccount
 	defer func() {
-		mock.ListAccounts = _listAccounts
+		mock.GetAccountByID = _getAccountByID
+		mock.CloseAccount = _closeAccount
 	}()
 	{
 		// mock calls to db
-		mock.ListAccounts = func() ([]*models.Account, error) {
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CloseAccount = func(id int64) (*models.Account, error) {
 			return nil, errors.New("error")
 		}
 	}
-	handler := http.HandlerFunc(app.listAccountsHandler)
+	handler := http.HandlerFunc(app.closeAccountHandler)
 	response := httptest.NewRecorder()
-
 	handler.ServeHTTP(response, req)
 	if response.Result().StatusCode != http.StatusInternalServerError {
 		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
 	}
 }
 
-func Test_application_deleteAccountHandler_success(t *testing.T) {
+func Test_application_closeAccountHandler_record_not_found(t *testing.T) {
 	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_deleteAccount := mock.DeleteAccount
+	_getAccountByID := mock.GetAccountByID
+	_closeAccount := mock.CloseAccount
 	defer func() {
-		mock.DeleteAccount = _deleteAccount
+		mock.GetAccountByID = _getAccountByID
+		mock.CloseAccount = _closeAccount
 	}()
 	{
 		// mock calls to db
-		mock.DeleteAccount = func(id int64) error {
-			return nil
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CloseAccount = func(id int64) (*models.Account, error) {
+			return nil, store.ErrRecordNotFound
 		}
 	}
-	handler := http.HandlerFunc(app.deleteAccountHandler)
+	handler := http.HandlerFunc(app.closeAccountHandler)
 	response := httptest.NewRecorder()
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusOK {
-		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
 	}
 }
 
-func Test_application_deleteAccountHandler_database_error(t *testing.T) {
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/", nil)
+func Test_application_closeAccountHandler_non_zero_balance(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/close", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_deleteAccount := mock.DeleteAccount
+	_getAccountByID := mock.GetAccountByID
+	_closeAccount := mock.CloseAccount
 	defer func() {
-		mock.DeleteAccount = _deleteAccount
+		mock.GetAccountByID = _getAccountByID
+		mock.CloseAccount = _closeAccount
 	}()
 	{
 		// mock calls to db
-		mock.DeleteAccount = func(id int64) error {
-			return errors.New("error")
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Balance: 100}, nil
+		}
+		mock.CloseAccount = func(id int64) (*models.Account, error) {
+			return nil, store.ErrNonZeroBalance
 		}
 	}
-	handler := http.HandlerFunc(app.deleteAccountHandler)
+	handler := http.HandlerFunc(app.closeAccountHandler)
 	response := httptest.NewRecorder()
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusInternalServerError {
-		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected status code: %d, but got %d", http.StatusConflict, response.Result().StatusCode)
 	}
 }
 
-func Test_application_deleteAccountHandler_record_not_found(t *testing.T) {
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/accounts/", nil)
+func Test_application_freezeAccountHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		freezeErr  error
+		statusCode int
+	}{
+		{"success", nil, http.StatusOK},
+		{"already frozen", store.ErrInvalidStatusTransition, http.StatusConflict},
+		{"record not found", store.ErrRecordNotFound, http.StatusNotFound},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/freeze", nil)
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			_getAccountByID := mock.GetAccountByID
+			_freezeAccount := mock.FreezeAccount
+			defer func() {
+				mock.GetAccountByID = _getAccountByID
+				mock.FreezeAccount = _freezeAccount
+			}()
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusActive}, nil
+				}
+				mock.FreezeAccount = func(id int64) (*models.Account, error) {
+					if e.freezeErr != nil {
+						return nil, e.freezeErr
+					}
+					return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusFrozen}, nil
+				}
+			}
+			handler := http.HandlerFunc(app.freezeAccountHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_freezeAccountHandler_not_permitted(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/freeze", nil)
+	req = authenticated(req)
+	handler := app.requirePermission(models.PermissionAccountsFreeze, app.freezeAccountHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected status code: %d, but got %d", http.StatusForbidden, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getAccountByIDHandler_closed(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+	}{
+		{"excluded by default", "/api/v1/accounts/1", http.StatusNotFound},
+		{"included when asked", "/api/v1/accounts/1?include_closed=true", http.StatusOK},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, e.url, nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			_getAccountByID := mock.GetAccountByID
+			defer func() {
+				mock.GetAccountByID = _getAccountByID
+			}()
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusClosed}, nil
+				}
+			}
+			handler := http.HandlerFunc(app.getAccountByIDHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_setOverdraftLimitHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		status     string
+		statusCode int
+	}{
+		{"success", `{"overdraft_limit": 50000}`, models.AccountStatusActive, http.StatusOK},
+		{"revoked", `{"overdraft_limit": 0}`, models.AccountStatusActive, http.StatusOK},
+		{"negative", `{"overdraft_limit": -1}`, models.AccountStatusActive, http.StatusUnprocessableEntity},
+		{"missing", `{}`, models.AccountStatusActive, http.StatusUnprocessableEntity},
+		{"closed account", `{"overdraft_limit": 50000}`, models.AccountStatusClosed, http.StatusConflict},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_setAccountOverdraftLimit := mock.SetAccountOverdraftLimit
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.SetAccountOverdraftLimit = _setAccountOverdraftLimit
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID, Status: e.status}, nil
+				}
+				mock.SetAccountOverdraftLimit = func(id int64, limit int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID, Status: e.status, OverdraftLimit: limit}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/1/overdraft", strings.NewReader(e.body))
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.setOverdraftLimitHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_setOverdraftLimitHandler_not_permitted(t *testing.T) {
+	teller := &models.User{ID: 3, Name: "Teller", Email: "teller@example.com", Role: models.RoleTeller}
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/accounts/1/overdraft", strings.NewReader(`{"overdraft_limit": 100}`))
+	req = app.contextSetUser(req, teller)
+	handler := app.requirePermission(models.PermissionAccountsOverdraft, app.setOverdraftLimitHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected status code: %d, but got %d", http.StatusForbidden, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getAccountByIDHandler_overdrawn_for(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1", nil)
+	req = authenticated(req)
 	rctx := chi.NewRouteContext()
 	rctx.URLParams.Add("id", "1")
 	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
-	_deleteAccount := mock.DeleteAccount
+	_getAccountByID := mock.GetAccountByID
 	defer func() {
-		mock.DeleteAccount = _deleteAccount
+		mock.GetAccountByID = _getAccountByID
 	}()
 	{
 		// mock calls to db
-		mock.DeleteAccount = func(id int64) error {
-			return store.ErrRecordNotFound
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			since := time.Now().Add(-time.Hour)
+			return &models.Account{
+				ID: id, UserID: testUser.ID, Balance: -100, OverdraftLimit: 500, Status: models.AccountStatusActive,
+				OverdrawnSince: &since, OverdrawnSeconds: 600,
+			}, nil
 		}
 	}
-	handler := http.HandlerFunc(app.deleteAccountHandler)
+	handler := http.HandlerFunc(app.getAccountByIDHandler)
 	response := httptest.NewRecorder()
 	handler.ServeHTTP(response, req)
-	if response.Result().StatusCode != http.StatusNotFound {
-		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+	var body struct {
+		Account struct {
+			OverdrawnSeconds    int64 `json:"overdrawn_seconds"`
+			OverdrawnForSeconds int64 `json:"overdrawn_for_seconds"`
+		} `json:"account"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	// the earlier ten minutes and the current hour
+	if body.Account.OverdrawnSeconds != 600 || body.Account.OverdrawnForSeconds < 4200 || body.Account.OverdrawnForSeconds > 4210 {
+		t.Errorf("expected 600 seconds stored and about 4200 in total, but got %+v", body.Account)
 	}
 }
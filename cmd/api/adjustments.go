@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+func (app *application) depositHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Amount int64  `json:"amount"`
+		Note   string `json:"note"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Amount > 0, "amount", "must be greater than zero")
+	validateNote(v, input.Note)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.adjustBalance(w, r, input.Amount, models.EntryReasonDeposit, input.Note)
+}
+
+func (app *application) withdrawalHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Amount int64  `json:"amount"`
+		Note   string `json:"note"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Amount > 0, "amount", "must be greater than zero")
+	validateNote(v, input.Note)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.adjustBalance(w, r, -input.Amount, models.EntryReasonWithdrawal, input.Note)
+}
+
+// adjustmentHandler books a manual, signed adjustment. Unlike deposits and withdrawals the
+// reason has to be given and a note explaining it is required.
+func (app *application) adjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Amount int64  `json:"amount"`
+		Reason string `json:"reason"`
+		Note   string `json:"note"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Amount != 0, "amount", "must not be zero")
+	v.Check(validator.PermittedValue(input.Reason, models.AdjustmentReasons...), "reason", "must be one of "+strings.Join(models.AdjustmentReasons, ", "))
+	v.Check(input.Note != "", "note", "must be provided")
+	validateNote(v, input.Note)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.adjustBalance(w, r, input.Amount, input.Reason, input.Note)
+}
+
+func validateNote(v *validator.Validator, note string) {
+	v.Check(len(note) <= 500, "note", "must not be more than 500 bytes long")
+}
+
+// adjustBalance books amount on the account in the URL on behalf of the authenticated user.
+func (app *application) adjustBalance(w http.ResponseWriter, r *http.Request, amount int64, reason, note string) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+
+	actorID := app.contextGetUser(r).ID
+	entry := &models.Entry{
+		AccountID: id,
+		Amount:    amount,
+		Reason:    reason,
+		ActorID:   &actorID,
+		Note:      note,
+	}
+	acc, err := app.store.Account.Adjust(r.Context(), entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		case errors.Is(err, store.ErrInsufficientBalance):
+			app.badRequestErrorResponse(w, r, err)
+			return
+		case errors.Is(err, store.ErrAccountClosed), errors.Is(err, store.ErrAccountFrozen):
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"account": acc, "entry": entry}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_depositHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/deposits", strings.NewReader(`{"amount": 500}`))
+	req = app.contextSetUser(req, testAdmin)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_adjustAccount := mock.AdjustAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.AdjustAccount = _adjustAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Balance: 1000}, nil
+		}
+		mock.AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+			if e.Amount != 500 || e.Reason != models.EntryReasonDeposit || e.ActorID == nil || *e.ActorID != testAdmin.ID {
+				t.Errorf("unexpected entry: %+v", e)
+			}
+			return &models.Account{ID: e.AccountID, UserID: testUser.ID, Balance: 1500}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.depositHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+}
+
+func Test_application_depositHandler_bad_input(t *testing.T) {
+	tests := []struct {
+		name               string
+		reqBody            string
+		expectedStatusCode int
+	}{
+		{"zero amount", `{"amount": 0}`, http.StatusUnprocessableEntity},
+		{"negative amount", `{"amount": -500}`, http.StatusUnprocessableEntity},
+		{"absolute balance", `{"balance": 500}`, http.StatusBadRequest},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/deposits", strings.NewReader(e.reqBody))
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.depositHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("expected status code: %d, but got %d", e.expectedStatusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_withdrawalHandler_insufficient_balance(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/withdrawals", strings.NewReader(`{"amount": 5000}`))
+	req = app.contextSetUser(req, testAdmin)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_adjustAccount := mock.AdjustAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.AdjustAccount = _adjustAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Balance: 1000}, nil
+		}
+		mock.AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+			if e.Amount != -5000 || e.Reason != models.EntryReasonWithdrawal {
+				t.Errorf("unexpected entry: %+v", e)
+			}
+			return nil, store.ErrInsufficientBalance
+		}
+	}
+	handler := http.HandlerFunc(app.withdrawalHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+}
+
+func Test_application_withdrawalHandler_frozen_account(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/withdrawals", strings.NewReader(`{"amount": 500}`))
+	req = app.contextSetUser(req, testAdmin)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_adjustAccount := mock.AdjustAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.AdjustAccount = _adjustAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Balance: 1000, Status: models.AccountStatusFrozen}, nil
+		}
+		mock.AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+			return nil, store.ErrAccountFrozen
+		}
+	}
+	handler := http.HandlerFunc(app.withdrawalHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusConflict {
+		t.Errorf("expected status code: %d, but got %d", http.StatusConflict, response.Result().StatusCode)
+	}
+}
+
+func Test_application_adjustmentHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		reqBody            string
+		expectedStatusCode int
+	}{
+		{"correction", `{"amount": -250, "reason": "correction", "note": "duplicate deposit"}`, http.StatusCreated},
+		{"unknown reason", `{"amount": -250, "reason": "because", "note": "duplicate deposit"}`, http.StatusUnprocessableEntity},
+		{"missing note", `{"amount": -250, "reason": "correction"}`, http.StatusUnprocessableEntity},
+		{"zero amount", `{"amount": 0, "reason": "fee", "note": "monthly fee"}`, http.StatusUnprocessableEntity},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_adjustAccount := mock.AdjustAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.AdjustAccount = _adjustAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Balance: 1000}, nil
+		}
+		mock.AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+			return &models.Account{ID: e.AccountID, UserID: testUser.ID, Balance: 1000 + e.Amount}, nil
+		}
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/adjustments", strings.NewReader(e.reqBody))
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.adjustmentHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("%s: expected status %d, but got %d", e.name, e.expectedStatusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_adjustmentHandler_database_error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/accounts/1/adjustments", strings.NewReader(`{"amount": 10, "reason": "interest", "note": "q1"}`))
+	req = app.contextSetUser(req, testAdmin)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_adjustAccount := mock.AdjustAccount
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.AdjustAccount = _adjustAccount
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+			return nil, errors.New("error")
+		}
+	}
+	handler := http.HandlerFunc(app.adjustmentHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	}
+}
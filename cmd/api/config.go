@@ -0,0 +1,381 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+type config struct {
+	port int
+	env  string
+	db   struct {
+		dsn          string
+		maxOpenConns int
+		maxIdleConns int
+		maxLifetime  time.Duration
+		maxIdleTime  time.Duration
+		timeouts     store.Timeouts
+	}
+	transfers struct {
+		crossCurrency     bool
+		exchangeRatesFile string
+	}
+	scheduler struct {
+		interval time.Duration
+	}
+	holds struct {
+		expiryInterval time.Duration
+	}
+	shutdownGracePeriod time.Duration
+	limiter             struct {
+		enabled bool
+		// ip applies to every request before it is authenticated, read to every client after
+		// that and money to the routes moving money on top of it
+		ip, read, money struct {
+			rps   float64
+			burst int
+		}
+	}
+	cors struct {
+		trustedOrigins []string
+	}
+	reconcile struct {
+		run bool
+		fix bool
+	}
+	// promoteAdmin is the email address of a registered user to make an admin
+	promoteAdmin string
+}
+
+// setting is a single configuration value. It can be given as a command-line flag, an
+// environment variable and a key of the config file, any of which may be empty when the
+// setting is not available that way.
+type setting struct {
+	flag  string
+	env   string
+	key   string
+	usage string
+	set   setter
+}
+
+// setter parses a setting value into its destination.
+type setter struct {
+	parse func(string) error
+	// isBool lets the setting be given as a bare -name flag
+	isBool bool
+}
+
+func (s setter) Set(value string) error {
+	return s.parse(value)
+}
+
+// settings lists every setting of cfg and sets cfg to the defaults.
+func (cfg *config) settings() []setting {
+	*cfg = config{}
+	cfg.port = 4000
+	cfg.env = "development"
+	cfg.db.maxOpenConns = 25
+	cfg.db.maxIdleConns = 25
+	cfg.db.maxLifetime = time.Hour
+	cfg.db.maxIdleTime = 15 * time.Minute
+	cfg.db.timeouts = store.Timeouts{
+		Read:     3 * time.Second,
+		Write:    5 * time.Second,
+		Transfer: 10 * time.Second,
+		Report:   30 * time.Second,
+	}
+	cfg.scheduler.interval = 30 * time.Second
+	cfg.holds.expiryInterval = time.Minute
+	cfg.shutdownGracePeriod = 30 * time.Second
+	cfg.limiter.enabled = true
+	cfg.limiter.ip.rps = 50
+	cfg.limiter.ip.burst = 100
+	cfg.limiter.read.rps = 10
+	cfg.limiter.read.burst = 20
+	cfg.limiter.money.rps = 1
+	cfg.limiter.money.burst = 5
+
+	return []setting{
+		{"port", "PORT", "port", "API server port", intSetting(&cfg.port)},
+		{"env", "ENV", "env", "environment (development|staging|production)", stringSetting(&cfg.env)},
+		{"db-dsn", "DSN", "db.dsn", "PostgreSQL DSN", stringSetting(&cfg.db.dsn)},
+		{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "db.max_open_conns", "PostgreSQL max open connections", intSetting(&cfg.db.maxOpenConns)},
+		{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "db.max_idle_conns", "PostgreSQL max idle connections", intSetting(&cfg.db.maxIdleConns)},
+		{"db-max-lifetime", "DB_MAX_LIFETIME", "db.max_lifetime", "PostgreSQL max connection lifetime, 0 keeps connections forever", durationSetting(&cfg.db.maxLifetime)},
+		{"db-max-idle-time", "DB_MAX_IDLE_TIME", "db.max_idle_time", "PostgreSQL max connection idle time, 0 keeps idle connections forever", durationSetting(&cfg.db.maxIdleTime)},
+		{"db-read-timeout", "DB_READ_TIMEOUT", "db.read_timeout", "timeout of lookups and listings", durationSetting(&cfg.db.timeouts.Read)},
+		{"db-write-timeout", "DB_WRITE_TIMEOUT", "db.write_timeout", "timeout of inserts and updates", durationSetting(&cfg.db.timeouts.Write)},
+		{"db-transfer-timeout", "DB_TRANSFER_TIMEOUT", "db.transfer_timeout", "timeout of a transfer, retries included", durationSetting(&cfg.db.timeouts.Transfer)},
+		{"db-report-timeout", "DB_REPORT_TIMEOUT", "db.report_timeout", "timeout of statements and reconciliation", durationSetting(&cfg.db.timeouts.Report)},
+		{"cross-currency-transfers", "CROSS_CURRENCY_TRANSFERS", "transfers.cross_currency", "convert transfers between accounts of different currencies", boolSetting(&cfg.transfers.crossCurrency)},
+		{"exchange-rates-file", "EXCHANGE_RATES_FILE", "transfers.exchange_rates_file", "JSON file holding the exchange rates", stringSetting(&cfg.transfers.exchangeRatesFile)},
+		{"scheduler-interval", "SCHEDULER_INTERVAL", "scheduler.interval", "how often scheduled transfers are executed, 0 turns the scheduler off", durationSetting(&cfg.scheduler.interval)},
+		{"hold-expiry-interval", "HOLD_EXPIRY_INTERVAL", "holds.expiry_interval", "how often expired holds are marked as expired, 0 turns it off", durationSetting(&cfg.holds.expiryInterval)},
+		{"shutdown-grace-period", "SHUTDOWN_GRACE_PERIOD", "shutdown_grace_period", "how long shutting down may take", durationSetting(&cfg.shutdownGracePeriod)},
+		{"limiter-enabled", "LIMITER_ENABLED", "limiter.enabled", "enable rate limiting", boolSetting(&cfg.limiter.enabled)},
+		{"limiter-ip-rps", "LIMITER_IP_RPS", "limiter.ip.rps", "rate limiter maximum requests per second of an IP address, authenticated or not", floatSetting(&cfg.limiter.ip.rps)},
+		{"limiter-ip-burst", "LIMITER_IP_BURST", "limiter.ip.burst", "rate limiter maximum burst of an IP address, authenticated or not", intSetting(&cfg.limiter.ip.burst)},
+		{"limiter-read-rps", "LIMITER_READ_RPS", "limiter.read.rps", "rate limiter maximum requests per second of a client", floatSetting(&cfg.limiter.read.rps)},
+		{"limiter-read-burst", "LIMITER_READ_BURST", "limiter.read.burst", "rate limiter maximum burst of a client", intSetting(&cfg.limiter.read.burst)},
+		{"limiter-money-rps", "LIMITER_MONEY_RPS", "limiter.money.rps", "rate limiter maximum transfers, deposits, withdrawals and adjustments per second of a client", floatSetting(&cfg.limiter.money.rps)},
+		{"limiter-money-burst", "LIMITER_MONEY_BURST", "limiter.money.burst", "rate limiter maximum burst of transfers, deposits, withdrawals and adjustments of a client", intSetting(&cfg.limiter.money.burst)},
+		{"cors-trusted-origins", "CORS_TRUSTED_ORIGINS", "cors.trusted_origins", "trusted CORS origins (space separated)", listSetting(&cfg.cors.trustedOrigins)},
+		{"reconcile", "", "", "report accounts whose balance disagrees with the ledger and exit", boolSetting(&cfg.reconcile.run)},
+		{"reconcile-fix", "", "", "with -reconcile, book correction entries for the drift", boolSetting(&cfg.reconcile.fix)},
+		{"promote-admin", "", "", "make the registered user with this email address an admin and exit", stringSetting(&cfg.promoteAdmin)},
+	}
+}
+
+// loadConfig reads the configuration from the command-line arguments, the environment and
+// the config file named by -config or CONFIG_FILE, in that order of precedence, on top of the
+// defaults. All problems found are reported at once.
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+	var cfg config
+	settings := cfg.settings()
+
+	// flags are only recorded here, they are applied last as they take precedence
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "optional YAML config file")
+	flags := make(map[string]*flagValue)
+	for _, s := range settings {
+		if s.flag != "" {
+			flags[s.flag] = &flagValue{isBool: s.set.isBool}
+			fs.Var(flags[s.flag], s.flag, s.usage)
+		}
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return config{}, err
+	}
+	if fs.NArg() > 0 {
+		return config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	var errs []error
+	if *configFile != "" {
+		values, err := readConfigFile(*configFile)
+		if err != nil {
+			return config{}, err
+		}
+		for _, s := range settings {
+			value, ok := values[s.key]
+			if s.key == "" || !ok {
+				continue
+			}
+			delete(values, s.key)
+			if err := s.set.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", *configFile, s.key, err))
+			}
+		}
+		for _, key := range sortedKeys(values) {
+			errs = append(errs, fmt.Errorf("%s: %s: unknown setting", *configFile, key))
+		}
+	}
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		if value := getenv(s.env); value != "" {
+			if err := s.set.Set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, s := range settings {
+		if f := flags[s.flag]; f != nil && f.set {
+			if err := s.set.Set(f.value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", s.flag, err))
+			}
+		}
+	}
+	// the values that could not be parsed keep their defaults, so the rest is still validated
+	errs = append(errs, cfg.validate())
+	if err = errors.Join(errs...); err != nil {
+		return config{}, err
+	}
+	return cfg, nil
+}
+
+// validate checks the configuration as a whole, all problems are reported at once.
+func (cfg *config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, a ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, a...))
+		}
+	}
+	check(cfg.port > 0 && cfg.port <= 65535, "port must be between 1 and 65535")
+	check(cfg.env == "development" || cfg.env == "staging" || cfg.env == "production", "env must be one of development, staging, production")
+	check(cfg.db.dsn != "", "db dsn must be provided")
+	check(cfg.db.maxOpenConns >= 0, "db max open connections must not be negative")
+	check(cfg.db.maxIdleConns >= 0, "db max idle connections must not be negative")
+	check(cfg.db.maxOpenConns == 0 || cfg.db.maxIdleConns <= cfg.db.maxOpenConns, "db max idle connections must not be more than max open connections")
+	for name, d := range map[string]time.Duration{
+		"db max lifetime":      cfg.db.maxLifetime,
+		"db max idle time":     cfg.db.maxIdleTime,
+		"db read timeout":      cfg.db.timeouts.Read,
+		"db write timeout":     cfg.db.timeouts.Write,
+		"db transfer timeout":  cfg.db.timeouts.Transfer,
+		"db report timeout":    cfg.db.timeouts.Report,
+		"scheduler interval":   cfg.scheduler.interval,
+		"hold expiry interval": cfg.holds.expiryInterval,
+	} {
+		check(d >= 0, "%s must not be negative", name)
+	}
+	check(cfg.shutdownGracePeriod > 0, "shutdown grace period must be greater than zero")
+	check(!cfg.transfers.crossCurrency || cfg.transfers.exchangeRatesFile != "", "exchange rates file must be provided when cross currency transfers are enabled")
+	if cfg.limiter.enabled {
+		check(cfg.limiter.ip.rps > 0, "limiter ip rps must be greater than zero")
+		check(cfg.limiter.ip.burst > 0, "limiter ip burst must be greater than zero")
+		check(cfg.limiter.read.rps > 0, "limiter read rps must be greater than zero")
+		check(cfg.limiter.read.burst > 0, "limiter read burst must be greater than zero")
+		check(cfg.limiter.money.rps > 0, "limiter money rps must be greater than zero")
+		check(cfg.limiter.money.burst > 0, "limiter money burst must be greater than zero")
+	}
+	for _, origin := range cfg.cors.trustedOrigins {
+		u, err := url.Parse(origin)
+		check(err == nil && u.Scheme != "" && u.Host != "" && u.Path == "", "cors trusted origin %q must be a scheme and a host", origin)
+	}
+	check(!cfg.reconcile.fix || cfg.reconcile.run, "-reconcile-fix requires -reconcile")
+	check(cfg.promoteAdmin == "" || !cfg.reconcile.run, "-promote-admin and -reconcile can not be combined")
+	return errors.Join(errs...)
+}
+
+// readConfigFile reads a YAML config file into its settings, keyed by their dotted path.
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	err = yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := make(map[string]string)
+	flattenConfig("", doc, values)
+	return values, nil
+}
+
+func flattenConfig(prefix string, doc map[string]any, values map[string]string) {
+	for k, v := range doc {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flattenConfig(key, v, values)
+		case []any:
+			items := make([]string, len(v))
+			for i := range v {
+				items[i] = fmt.Sprint(v[i])
+			}
+			values[key] = strings.Join(items, " ")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flagValue records a flag so that it can be applied after the other sources.
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value, f.set = value, true
+	return nil
+}
+
+// IsBoolFlag lets boolean settings be given as a bare -name, the others take the next
+// argument as their value.
+func (f *flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func stringSetting(dest *string) setter {
+	return setter{parse: func(s string) error {
+		*dest = s
+		return nil
+	}}
+}
+
+func intSetting(dest *int) setter {
+	return setter{parse: func(s string) error {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		*dest = i
+		return nil
+	}}
+}
+
+func floatSetting(dest *float64) setter {
+	return setter{parse: func(s string) error {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		*dest = f
+		return nil
+	}}
+}
+
+func boolSetting(dest *bool) setter {
+	parse := func(s string) error {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		*dest = b
+		return nil
+	}
+	return setter{parse: parse, isBool: true}
+}
+
+func durationSetting(dest *time.Duration) setter {
+	return setter{parse: func(s string) error {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", s)
+		}
+		*dest = d
+		return nil
+	}}
+}
+
+func listSetting(dest *[]string) setter {
+	return setter{parse: func(s string) error {
+		*dest = strings.Fields(s)
+		return nil
+	}}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_loadConfig_precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+port: 5000
+env: staging
+db:
+  dsn: postgres://file
+  max_open_conns: 10
+  max_idle_conns: 5
+  read_timeout: 1s
+cors:
+  trusted_origins:
+    - https://bank.example.com
+    - https://admin.example.com
+`)
+	env := map[string]string{
+		"CONFIG_FILE":     path,
+		"ENV":             "production",
+		"DSN":             "postgres://env",
+		"DB_MAX_LIFETIME": "30m",
+	}
+	cfg, err := loadConfig([]string{"-db-dsn=postgres://flag", "-reconcile"}, func(key string) string {
+		return env[key]
+	})
+	if err != nil {
+		t.Fatalf("expected the config to load, but got %v", err)
+	}
+	if cfg.port != 5000 {
+		t.Errorf("expected the port of the file, but got %d", cfg.port)
+	}
+	if cfg.env != "production" {
+		t.Errorf("expected the environment to override the file, but got %q", cfg.env)
+	}
+	if cfg.db.dsn != "postgres://flag" {
+		t.Errorf("expected the flag to override the environment, but got %q", cfg.db.dsn)
+	}
+	if cfg.db.maxOpenConns != 10 || cfg.db.maxIdleConns != 5 || cfg.db.timeouts.Read != time.Second {
+		t.Errorf("expected the db settings of the file, but got %+v", cfg.db)
+	}
+	if cfg.db.maxLifetime != 30*time.Minute || cfg.db.maxIdleTime != 15*time.Minute {
+		t.Errorf("expected the connection lifetimes of the environment and the default, but got %s and %s", cfg.db.maxLifetime, cfg.db.maxIdleTime)
+	}
+	if cfg.db.timeouts.Transfer != 10*time.Second {
+		t.Errorf("expected the default transfer timeout, but got %s", cfg.db.timeouts.Transfer)
+	}
+	if len(cfg.cors.trustedOrigins) != 2 || cfg.cors.trustedOrigins[1] != "https://admin.example.com" {
+		t.Errorf("expected the trusted origins of the file, but got %v", cfg.cors.trustedOrigins)
+	}
+	if !cfg.reconcile.run {
+		t.Error("expected the bare boolean flag to be set")
+	}
+}
+
+func Test_loadConfig_flag_values(t *testing.T) {
+	cfg, err := loadConfig([]string{"-db-dsn", "postgres://flag", "-port", "5000", "-reconcile", "-limiter-enabled=false"}, func(string) string {
+		return ""
+	})
+	if err != nil {
+		t.Fatalf("expected the config to load, but got %v", err)
+	}
+	if cfg.db.dsn != "postgres://flag" || cfg.port != 5000 {
+		t.Errorf("expected the values following the flags, but got dsn %q and port %d", cfg.db.dsn, cfg.port)
+	}
+	if !cfg.reconcile.run || cfg.limiter.enabled {
+		t.Errorf("expected the bare and the explicit boolean flags to be applied, but got %t and %t", cfg.reconcile.run, cfg.limiter.enabled)
+	}
+}
+
+func Test_loadConfig_errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "defaults need a dsn",
+			expected: []string{"db dsn must be provided"},
+		},
+		{
+			name: "all problems at once",
+			env: map[string]string{
+				"PORT":                     "70000",
+				"ENV":                      "testing",
+				"DSN":                      "postgres://env",
+				"DB_MAX_IDLE_CONNS":        "50",
+				"CROSS_CURRENCY_TRANSFERS": "true",
+				"LIMITER_MONEY_RPS":        "0",
+			},
+			args: []string{"-cors-trusted-origins=example.com", "-reconcile-fix"},
+			expected: []string{
+				"port must be between 1 and 65535",
+				"env must be one of",
+				"db max idle connections must not be more than max open connections",
+				"exchange rates file must be provided",
+				"limiter money rps must be greater than zero",
+				`cors trusted origin "example.com"`,
+				"-reconcile-fix requires -reconcile",
+			},
+		},
+		{
+			name: "malformed values",
+			env: map[string]string{
+				"DSN":             "postgres://env",
+				"PORT":            "http",
+				"DB_READ_TIMEOUT": "3",
+			},
+			args:     []string{"-limiter-enabled=maybe"},
+			expected: []string{`PORT: "http" is not an integer`, `DB_READ_TIMEOUT: "3" is not a duration`, `-limiter-enabled: "maybe" is not a boolean`},
+		},
+		{
+			name: "malformed and invalid values",
+			env: map[string]string{
+				"DB_READ_TIMEOUT":   "3",
+				"LIMITER_MONEY_RPS": "0",
+			},
+			expected: []string{`DB_READ_TIMEOUT: "3" is not a duration`, "db dsn must be provided", "limiter money rps must be greater than zero"},
+		},
+		{
+			name:     "promote admin and reconcile",
+			args:     []string{"-db-dsn=postgres://flag", "-promote-admin=admin@example.com", "-reconcile"},
+			expected: []string{"-promote-admin and -reconcile can not be combined"},
+		},
+		{
+			name:     "stray arguments",
+			args:     []string{"-db-dsn=postgres://flag", "-reconcile", "true"},
+			expected: []string{"unexpected arguments: true"},
+		},
+		{
+			name:     "unknown file settings",
+			file:     "db:\n  dsn: postgres://file\n  max_conns: 10\n",
+			expected: []string{"db.max_conns: unknown setting"},
+		},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			env := map[string]string{}
+			for k, v := range e.env {
+				env[k] = v
+			}
+			if e.file != "" {
+				env["CONFIG_FILE"] = writeConfigFile(t, e.file)
+			}
+			_, err := loadConfig(e.args, func(key string) string {
+				return env[key]
+			})
+			if err == nil {
+				t.Fatalf("%s: expected an error", e.name)
+			}
+			for _, msg := range e.expected {
+				if !strings.Contains(err.Error(), msg) {
+					t.Errorf("%s: expected the error to contain %q, but got %q", e.name, msg, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type contextKey string
+
+const (
+	userContextKey      = contextKey("user")
+	requestIDContextKey = contextKey("request_id")
+)
+
+func (app *application) contextSetUser(r *http.Request, user *models.User) *http.Request {
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	return r.WithContext(ctx)
+}
+
+// contextGetUser returns the user set by the authenticate middleware, it is only called
+// from handlers behind that middleware so a missing value is a programming error.
+func (app *application) contextGetUser(r *http.Request) *models.User {
+	user, ok := r.Context().Value(userContextKey).(*models.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
+
+func (app *application) contextSetRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// contextGetRequestID returns the id set by the requestID middleware, or "" for requests that
+// did not pass through it.
+func (app *application) contextGetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"database/sql"
+	"expvar"
+	"runtime"
+	"time"
+)
+
+// dbStats is sql.DBStats as served on /debug/vars, durations are in seconds.
+type dbStats struct {
+	MaxOpenConnections int     `json:"max_open_connections"`
+	OpenConnections    int     `json:"open_connections"`
+	InUse              int     `json:"in_use"`
+	Idle               int     `json:"idle"`
+	WaitCount          int64   `json:"wait_count"`
+	WaitDuration       float64 `json:"wait_duration_seconds"`
+	MaxIdleClosed      int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64   `json:"max_lifetime_closed"`
+}
+
+func newDBStats(s sql.DBStats) dbStats {
+	return dbStats{
+		MaxOpenConnections: s.MaxOpenConnections,
+		OpenConnections:    s.OpenConnections,
+		InUse:              s.InUse,
+		Idle:               s.Idle,
+		WaitCount:          s.WaitCount,
+		WaitDuration:       s.WaitDuration.Seconds(),
+		MaxIdleClosed:      s.MaxIdleClosed,
+		MaxIdleTimeClosed:  s.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  s.MaxLifetimeClosed,
+	}
+}
+
+// publishDebugVars adds the connection pool statistics of db and runtime figures to the
+// expvar variables. A growing wait_count shows requests queueing for a connection. It may only
+// be called once.
+func publishDebugVars(db *sql.DB) {
+	expvar.Publish("database", expvar.Func(func() any {
+		return newDBStats(db.Stats())
+	}))
+	expvar.Publish("goroutines", expvar.Func(func() any {
+		return runtime.NumGoroutine()
+	}))
+	expvar.Publish("timestamp", expvar.Func(func() any {
+		return time.Now().Unix()
+	}))
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func Test_newDBStats(t *testing.T) {
+	stats := newDBStats(sql.DBStats{
+		MaxOpenConnections: 25,
+		OpenConnections:    20,
+		InUse:              18,
+		Idle:               2,
+		WaitCount:          7,
+		WaitDuration:       1500 * time.Millisecond,
+	})
+	body, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	err = json.Unmarshal(body, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]float64{
+		"max_open_connections":  25,
+		"open_connections":      20,
+		"in_use":                18,
+		"idle":                  2,
+		"wait_count":            7,
+		"wait_duration_seconds": 1.5,
+	} {
+		if got[key] != expected {
+			t.Errorf("expected %s to be %v, but got %v", key, expected, got[key])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+)
+
+func (app *application) accountStatementHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	var filter models.StatementFilter
+	if filter.Since, err = app.readTime(qs, "from"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	if filter.Until, err = app.readTime(qs, "to"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	if filter.Since != nil && filter.Until != nil && !filter.Since.Before(*filter.Until) {
+		app.badRequestErrorResponse(w, r, errors.New("from must be before to"))
+		return
+	}
+	if filter.Pagination, err = app.readPagination(qs); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	statement, metadata, err := app.store.Entry.Statement(r.Context(), id, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"statement": statement, "metadata": metadata}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
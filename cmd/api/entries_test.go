@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_accountStatementHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/entries?from=2023-01-01&to=2023-02-01&page=2&page_size=10", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getStatement := mock.GetStatement
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetStatement = _getStatement
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetStatement = func(accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+			if filter.Since == nil || filter.Until == nil || filter.Page != 2 || filter.PageSize != 10 {
+				t.Errorf("unexpected filter: %+v", filter)
+			}
+			toReturn := &models.Statement{
+				AccountID:      accountID,
+				OpeningBalance: 100,
+				ClosingBalance: 150,
+				Lines: []*models.StatementLine{
+					{Entry: models.Entry{ID: 1, AccountID: accountID, Amount: 50}, RunningBalance: 150},
+				},
+			}
+			return toReturn, models.CalculateMetadata(11, filter.Pagination), nil
+		}
+	}
+	handler := http.HandlerFunc(app.accountStatementHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
+
+func Test_application_accountStatementHandler_bad_input(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"malformed date", "from=last-week"},
+		{"inverted period", "from=2023-02-01&to=2023-01-01"},
+		{"page out of range", "page=0"},
+		{"page size out of range", "page_size=1000"},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/entries?"+e.query, nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.accountStatementHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_accountStatementHandler_no_records_found(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/10/entries", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "10")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getStatement := mock.GetStatement
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetStatement = _getStatement
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetStatement = func(accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+			return nil, models.Metadata{}, store.ErrRecordNotFound
+		}
+	}
+	handler := http.HandlerFunc(app.accountStatementHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
+func Test_application_accountStatementHandler_other_users_account(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/2/entries", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "2")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getStatement := mock.GetStatement
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetStatement = _getStatement
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID + 1}, nil
+		}
+		mock.GetStatement = func(accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+			t.Error("expected the statement of another user's account not to be read")
+			return nil, models.Metadata{}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.accountStatementHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
+func Test_application_accountStatementHandler_database_error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/entries", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getStatement := mock.GetStatement
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetStatement = _getStatement
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetStatement = func(accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+			return nil, models.Metadata{}, errors.New("error")
+		}
+	}
+	handler := http.HandlerFunc(app.accountStatementHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	}
+}
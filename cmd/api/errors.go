@@ -1,11 +1,15 @@
 package main
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (app *application) logError(r *http.Request, err error) {
 	app.logger.PrintError(err, map[string]any{
+		"request_id":     app.contextGetRequestID(r),
 		"request_method": r.Method,
 		"request_url":    r.URL.String(),
 	})
@@ -30,7 +34,53 @@ func (app *application) badRequestErrorResponse(w http.ResponseWriter, r *http.R
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
 }
 
+func (app *application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+}
+
 func (app *application) notFoundRespose(w http.ResponseWriter, r *http.Request) {
 	messsage := "the requested resource could not be found"
 	app.errorResponse(w, r, http.StatusNotFound, messsage)
 }
+
+func (app *application) idempotencyKeyConflictResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the idempotency key has already been used with a different request payload"
+	app.errorResponse(w, r, http.StatusUnprocessableEntity, message)
+}
+
+func (app *application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
+	message := "invalid authentication credentials"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	message := "invalid or missing authentication token"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you must be authenticated to access this resource"
+	app.errorResponse(w, r, http.StatusUnauthorized, message)
+}
+
+func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, message)
+}
+
+func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.errorResponse(w, r, http.StatusConflict, err.Error())
+}
+
+// rateLimitExceededResponse tells the client to come back after retryAfter, rounded up to
+// whole seconds as Retry-After requires.
+func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	message := "rate limit exceeded"
+	app.errorResponse(w, r, http.StatusTooManyRequests, message)
+}
@@ -1,19 +1,124 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/store"
+)
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// readinessTimeout bounds each dependency check of the readiness probe.
+const readinessTimeout = 2 * time.Second
+
+const (
+	checkStatusUp   = "up"
+	checkStatusDown = "down"
 )
 
 func (app *application) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	response := make(map[string]string, 0)
-	response["env"] = app.cfg.env
-	response["status"] = "healthy"
-	resBytes, err := json.Marshal(response)
+	err := app.writeJSON(w, envelope{"env": app.cfg.env, "status": "healthy"}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// livezHandler reports that the process is up and serving, it deliberately checks no
+// dependency so that an unreachable database does not get the instance restarted.
+func (app *application) livezHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, envelope{"status": "alive"}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readyzHandler reports whether the instance can serve requests. Every dependency is checked
+// and reported, any check that is down makes the response a 503. The endpoint is public, so
+// the errors behind failed checks are logged rather than reported.
+func (app *application) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]envelope{
+		"database":   app.checkDatabase(r.Context()),
+		"migrations": app.checkMigrations(r.Context()),
+	}
+	status, statusCode := "ready", http.StatusOK
+	for _, check := range checks {
+		if check["status"] != checkStatusUp {
+			status, statusCode = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	err := app.writeJSON(w, envelope{"status": status, "checks": checks, "build": buildInfo()}, statusCode, nil)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"error": true, "message": "something went wrong, try again later"}`))
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) checkDatabase(ctx context.Context) envelope {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := app.store.Health.Ping(ctx)
+	if err != nil {
+		app.logger.PrintError(err, map[string]any{"check": "database"})
+		return envelope{"status": checkStatusDown, "error": "the database can not be reached"}
+	}
+	return envelope{"status": checkStatusUp, "latency": time.Since(start).String()}
+}
+
+func (app *application) checkMigrations(ctx context.Context) envelope {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	migration, err := app.store.Health.MigrationStatus(ctx)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			return envelope{"status": checkStatusDown, "error": "the database has not been migrated"}
+		default:
+			app.logger.PrintError(err, map[string]any{"check": "migrations"})
+			return envelope{"status": checkStatusDown, "error": "the migration status can not be read"}
+		}
+	}
+	if migration.Dirty {
+		return envelope{"status": checkStatusDown, "version": migration.Version, "dirty": true, "error": "the last migration failed"}
+	}
+	// the instance only serves the schema it was built for, older or newer ones may lack
+	// columns it uses
+	if migration.Version != store.SchemaVersion {
+		return envelope{
+			"status":           checkStatusDown,
+			"version":          migration.Version,
+			"expected_version": store.SchemaVersion,
+			"dirty":            false,
+			"error":            "the database is not at the expected migration",
+		}
+	}
+	return envelope{"status": checkStatusUp, "version": migration.Version, "dirty": false}
+}
+
+// buildInfo describes the running binary, the vcs fields are only known for binaries built
+// from a repository checkout.
+func buildInfo() envelope {
+	info := envelope{"version": version}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info["go_version"] = bi.GoVersion
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info["revision"] = s.Value
+		case "vcs.time":
+			info["revision_time"] = s.Value
+		case "vcs.modified":
+			info["modified"] = s.Value == "true"
+		}
 	}
-	w.WriteHeader(http.StatusOK)
-	w.Write(resBytes)
+	return info
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
 )
 
 func Test_application_healthcheckHandler(t *testing.T) {
@@ -34,3 +41,84 @@ func Test_application_healthcheckHandler(t *testing.T) {
 
 	}
 }
+
+func Test_application_livezHandler(t *testing.T) {
+	_ping := mock.Ping
+	defer func() {
+		mock.Ping = _ping
+	}()
+	{
+		// mock calls to db
+		mock.Ping = func() error {
+			return errors.New("connection refused")
+		}
+	}
+	response := httptest.NewRecorder()
+	app.livezHandler(response, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status %d without checking the database, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
+
+func Test_application_readyzHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		ping               error
+		migration          *models.MigrationStatus
+		migrationErr       error
+		expectedStatusCode int
+		expectedChecks     map[string]string
+	}{
+		{"ready", nil, &models.MigrationStatus{Version: store.SchemaVersion}, nil, http.StatusOK, map[string]string{"database": "up", "migrations": "up"}},
+		{"database down", errors.New("connection refused"), nil, errors.New("connection refused"), http.StatusServiceUnavailable, map[string]string{"database": "down", "migrations": "down"}},
+		{"dirty migration", nil, &models.MigrationStatus{Version: store.SchemaVersion, Dirty: true}, nil, http.StatusServiceUnavailable, map[string]string{"database": "up", "migrations": "down"}},
+		{"pending migrations", nil, &models.MigrationStatus{Version: store.SchemaVersion - 1}, nil, http.StatusServiceUnavailable, map[string]string{"database": "up", "migrations": "down"}},
+		{"newer migrations", nil, &models.MigrationStatus{Version: store.SchemaVersion + 1}, nil, http.StatusServiceUnavailable, map[string]string{"database": "up", "migrations": "down"}},
+		{"not migrated", nil, nil, store.ErrRecordNotFound, http.StatusServiceUnavailable, map[string]string{"database": "up", "migrations": "down"}},
+	}
+	_ping := mock.Ping
+	_migrationStatus := mock.MigrationStatus
+	defer func() {
+		mock.Ping = _ping
+		mock.MigrationStatus = _migrationStatus
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			{
+				// mock calls to db
+				mock.Ping = func() error {
+					return e.ping
+				}
+				mock.MigrationStatus = func() (*models.MigrationStatus, error) {
+					return e.migration, e.migrationErr
+				}
+			}
+			response := httptest.NewRecorder()
+			app.readyzHandler(response, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("%s: expected status %d, but got %d", e.name, e.expectedStatusCode, response.Result().StatusCode)
+			}
+			if strings.Contains(response.Body.String(), "connection refused") {
+				t.Errorf("%s: expected the database error not to be reported, but got %s", e.name, response.Body.String())
+			}
+			var body struct {
+				Checks map[string]struct {
+					Status string `json:"status"`
+				} `json:"checks"`
+				Build map[string]any `json:"build"`
+			}
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, expected := range e.expectedChecks {
+				if body.Checks[name].Status != expected {
+					t.Errorf("%s: expected the %s check to be %s, but got %q", e.name, name, expected, body.Checks[name].Status)
+				}
+			}
+			if body.Build["version"] != version {
+				t.Errorf("%s: expected the build version %q, but got %v", e.name, version, body.Build["version"])
+			}
+		})
+	}
+}
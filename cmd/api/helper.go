@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
+	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -29,20 +35,48 @@ func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dest an
 }
 
 func (app *application) writeJSON(w http.ResponseWriter, data envelope, statusCode int, headers http.Header) error {
-	resBytes, err := json.Marshal(data)
+	resBytes, err := marshalJSON(data)
 	if err != nil {
 		return err
 	}
 	for k, v := range headers {
 		w.Header()[k] = v
 	}
-	resBytes = append(resBytes, '\n')
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	w.Write(resBytes)
 	return nil
 }
 
+// marshalJSON encodes data exactly the way writeJSON writes it to the client.
+func marshalJSON(data envelope) ([]byte, error) {
+	resBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(resBytes, '\n'), nil
+}
+
+// writeRawJSON writes an already encoded json body, used to replay recorded responses.
+func (app *application) writeRawJSON(w http.ResponseWriter, body []byte, statusCode int, headers http.Header) {
+	for k, v := range headers {
+		w.Header()[k] = v
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// requestHash returns a hex encoded sha256 of the json encoding of input.
+func requestHash(input any) (string, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 func (app *application) parseReqParam(r *http.Request, field string) (int64, error) {
 	idStr := chi.URLParam(r, field)
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -51,3 +85,79 @@ func (app *application) parseReqParam(r *http.Request, field string) (int64, err
 	}
 	return id, nil
 }
+
+// readInt64 parses an optional integer query string value, returning 0 when it is absent.
+func (app *application) readInt64(qs url.Values, key string) (int64, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return 0, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer value", key)
+	}
+	return i, nil
+}
+
+// readTime parses an optional RFC 3339 timestamp or YYYY-MM-DD date from the query string,
+// returning nil when it is absent.
+func (app *application) readTime(qs url.Values, key string) (*time.Time, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be a RFC 3339 timestamp or a YYYY-MM-DD date", key)
+}
+
+// readInt parses an optional integer query string value, returning defaultValue when it is absent.
+func (app *application) readInt(qs url.Values, key string, defaultValue int) (int, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer value", key)
+	}
+	return i, nil
+}
+
+// readBool reads a boolean query string value, false when it is absent.
+func (app *application) readBool(qs url.Values, key string) (bool, error) {
+	s := qs.Get(key)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean value", key)
+	}
+	return b, nil
+}
+
+// readPagination reads the page and page_size query string values.
+func (app *application) readPagination(qs url.Values) (models.Pagination, error) {
+	var (
+		p   models.Pagination
+		err error
+	)
+	if p.Page, err = app.readInt(qs, "page", 1); err != nil {
+		return p, err
+	}
+	if p.PageSize, err = app.readInt(qs, "page_size", 20); err != nil {
+		return p, err
+	}
+	if p.Page < 1 || p.Page > 10_000_000 {
+		return p, errors.New("page must be between 1 and 10 million")
+	}
+	if p.PageSize < 1 || p.PageSize > 100 {
+		return p, errors.New("page_size must be between 1 and 100")
+	}
+	return p, nil
+}
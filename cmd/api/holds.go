@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+func (app *application) placeHoldHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AccountID   int64      `json:"account_id"`
+		ToAccountID int64      `json:"to_account_id"`
+		Amount      int64      `json:"amount"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	now := time.Now()
+	user := app.contextGetUser(r)
+	hold := &models.Hold{
+		AccountID:   input.AccountID,
+		ToAccountID: input.ToAccountID,
+		UserID:      user.ID,
+		Amount:      input.Amount,
+		ExpiresAt:   now.Add(models.DefaultHoldDuration),
+	}
+	if input.ExpiresAt != nil {
+		hold.ExpiresAt = *input.ExpiresAt
+	}
+	v := validator.New()
+	if models.ValidateHold(v, hold, now); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// customers can only hold money on their own accounts
+	payer, err := app.store.Account.Get(r.Context(), input.AccountID)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if payer == nil || !canAccessAccount(user, payer) {
+		app.badRequestErrorResponse(w, r, store.ErrInvalidPayer)
+		return
+	}
+
+	err = app.store.Hold.Place(r.Context(), hold)
+	if err != nil {
+		switch {
+		case isTransferRejection(err):
+			app.badRequestErrorResponse(w, r, err)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"hold": hold}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	hold, _, ok := app.getAccessibleHold(w, r, id)
+	if !ok {
+		return
+	}
+	err = app.writeJSON(w, envelope{"hold": hold}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// captureHoldHandler turns the hold into a transfer of the amount given, or of the whole hold
+// when no amount is given. Whatever is not captured is released.
+func (app *application) captureHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Amount int64 `json:"amount"`
+	}
+	// the body is optional, an empty one captures the whole hold
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestErrorResponse(w, r, err)
+			return
+		}
+	}
+	v := validator.New()
+	if v.Check(input.Amount >= 0, "amount", "must not be negative"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, payer, ok := app.getAccessibleHold(w, r, id)
+	if !ok {
+		return
+	}
+	hold, transfer, err := app.store.Hold.Capture(r.Context(), id, input.Amount)
+	if err == nil || isTransferRejection(err) {
+		amount := input.Amount
+		if transfer != nil {
+			amount = transfer.Amount
+		}
+		app.metrics.observeTransfer(payer.Currency, amount, err)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		case errors.Is(err, store.ErrHoldNotActive), errors.Is(err, store.ErrHoldExpired):
+			app.conflictResponse(w, r, err)
+			return
+		case errors.Is(err, store.ErrCaptureExceedsHold):
+			app.failedValidationResponse(w, r, map[string]string{"amount": "must not be more than the amount held"})
+			return
+		case isTransferRejection(err):
+			app.badRequestErrorResponse(w, r, err)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"hold": hold, "transfer": transfer}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// releaseHoldHandler gives the funds of the hold back to its payer. Only the payee, or a user
+// who may operate on every account, can release a hold, otherwise the payer could take back
+// funds the payee has been promised.
+func (app *application) releaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	h, _, ok := app.getAccessibleHold(w, r, id)
+	if !ok {
+		return
+	}
+	user := app.contextGetUser(r)
+	if !user.HasPermission(models.PermissionAccountsAccessAll) {
+		payee, err := app.store.Account.Get(r.Context(), h.ToAccountID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if payee.UserID != user.ID {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+	hold, err := app.store.Hold.Release(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		case errors.Is(err, store.ErrHoldNotActive), errors.Is(err, store.ErrHoldExpired):
+			app.conflictResponse(w, r, err)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.logger.PrintInfo("hold released", map[string]any{
+		"hold_id":    hold.ID,
+		"account_id": hold.AccountID,
+		"amount":     hold.Amount,
+		"actor_id":   user.ID,
+	})
+	err = app.writeJSON(w, envelope{"hold": hold}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAccountHoldsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	holds, err := app.store.Hold.ListForAccount(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"holds": holds}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// accountBalanceHandler reports the ledger balance of the account next to the amount held on
+// it and what is left available to spend.
+func (app *application) accountBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	balance, err := app.store.Hold.Balance(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"balance": balance}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAccessibleHold fetches the hold and the account it is placed on if the authenticated
+// user holds that account or the payee account, or may operate on every account. Other
+// users' holds are reported as not found. The error response has already been written when
+// ok is false.
+func (app *application) getAccessibleHold(w http.ResponseWriter, r *http.Request, id int64) (h *models.Hold, payer *models.Account, ok bool) {
+	h, err := app.store.Hold.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return nil, nil, false
+		default:
+			app.serverErrorResponse(w, r, err)
+			return nil, nil, false
+		}
+	}
+	user := app.contextGetUser(r)
+	payer, err = app.store.Account.Get(r.Context(), h.AccountID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, nil, false
+	}
+	if canAccessAccount(user, payer) {
+		return h, payer, true
+	}
+	payee, err := app.store.Account.Get(r.Context(), h.ToAccountID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, nil, false
+	}
+	if payee.UserID != user.ID {
+		app.notFoundRespose(w, r)
+		return nil, nil, false
+	}
+	return h, payer, true
+}
+
+// runHoldExpiry marks the holds that expired as expired every interval, until ctx is done.
+func (app *application) runHoldExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := app.store.Hold.ExpireStale(ctx, time.Now())
+		if err != nil {
+			app.logger.PrintError(err, map[string]any{"holds": "expire"})
+		} else if expired > 0 {
+			app.logger.PrintInfo("holds expired", map[string]any{"count": expired})
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
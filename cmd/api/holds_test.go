@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_placeHoldHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		payerOwner int64
+		placeErr   error
+		statusCode int
+	}{
+		{"success", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, nil, http.StatusCreated},
+		{"explicit expiry", fmt.Sprintf(`{"account_id": 1, "to_account_id": 2, "amount": 500, "expires_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339)), testUser.ID, nil, http.StatusCreated},
+		{"expiry in the past", fmt.Sprintf(`{"account_id": 1, "to_account_id": 2, "amount": 500, "expires_at": %q}`, time.Now().Add(-time.Hour).Format(time.RFC3339)), testUser.ID, nil, http.StatusUnprocessableEntity},
+		{"expiry too far away", fmt.Sprintf(`{"account_id": 1, "to_account_id": 2, "amount": 500, "expires_at": %q}`, time.Now().Add(60*24*time.Hour).Format(time.RFC3339)), testUser.ID, nil, http.StatusUnprocessableEntity},
+		{"zero amount", `{"account_id": 1, "to_account_id": 2, "amount": 0}`, testUser.ID, nil, http.StatusUnprocessableEntity},
+		{"same account", `{"account_id": 1, "to_account_id": 1, "amount": 500}`, testUser.ID, nil, http.StatusUnprocessableEntity},
+		{"other users account", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testAdmin.ID, nil, http.StatusBadRequest},
+		{"insufficient balance", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrInsufficientBalance, http.StatusBadRequest},
+		{"frozen account", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrAccountFrozen, http.StatusBadRequest},
+		{"limit exceeded", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrLimitExceeded, http.StatusBadRequest},
+		{"unknown payee", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrInvalidPayee, http.StatusBadRequest},
+		{"closed payee", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrAccountClosed, http.StatusBadRequest},
+		{"payee in another currency", `{"account_id": 1, "to_account_id": 2, "amount": 500}`, testUser.ID, store.ErrCurrencyMismatch, http.StatusBadRequest},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_placeHold := mock.PlaceHold
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.PlaceHold = _placeHold
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			var placed *models.Hold
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: e.payerOwner, Currency: "USD", Status: models.AccountStatusActive}, nil
+				}
+				mock.PlaceHold = func(h *models.Hold) error {
+					placed = h
+					return e.placeErr
+				}
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/holds", strings.NewReader(e.body))
+			req = authenticated(req)
+			handler := http.HandlerFunc(app.placeHoldHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if e.statusCode == http.StatusCreated && (placed == nil || placed.UserID != testUser.ID || placed.ExpiresAt.IsZero()) {
+				t.Errorf("expected the hold to be placed by the user with an expiry, but got %+v", placed)
+			}
+		})
+	}
+}
+
+func Test_application_captureHoldHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		payerOwner     int64
+		payeeOwner     int64
+		captureErr     error
+		statusCode     int
+		expectedAmount int64
+	}{
+		{"full capture", ``, testUser.ID, testAdmin.ID, nil, http.StatusOK, 0},
+		{"partial capture", `{"amount": 200}`, testUser.ID, testAdmin.ID, nil, http.StatusOK, 200},
+		{"captured by the payee", `{"amount": 200}`, testAdmin.ID, testUser.ID, nil, http.StatusOK, 200},
+		{"other users hold", `{"amount": 200}`, testAdmin.ID, testAdmin.ID, nil, http.StatusNotFound, 0},
+		{"negative amount", `{"amount": -1}`, testUser.ID, testAdmin.ID, nil, http.StatusUnprocessableEntity, 0},
+		{"more than held", `{"amount": 900}`, testUser.ID, testAdmin.ID, store.ErrCaptureExceedsHold, http.StatusUnprocessableEntity, 900},
+		{"already captured", ``, testUser.ID, testAdmin.ID, store.ErrHoldNotActive, http.StatusConflict, 0},
+		{"expired", ``, testUser.ID, testAdmin.ID, store.ErrHoldExpired, http.StatusConflict, 0},
+		{"payee closed", ``, testUser.ID, testAdmin.ID, store.ErrAccountClosed, http.StatusBadRequest, 0},
+	}
+	_getHold := mock.GetHold
+	_getAccountByID := mock.GetAccountByID
+	_captureHold := mock.CaptureHold
+	defer func() {
+		mock.GetHold = _getHold
+		mock.GetAccountByID = _getAccountByID
+		mock.CaptureHold = _captureHold
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			captured := false
+			{
+				// mock calls to db
+				mock.GetHold = func(id int64) (*models.Hold, error) {
+					return &models.Hold{ID: id, AccountID: 1, ToAccountID: 2, Amount: 500, Status: models.HoldStatusActive}, nil
+				}
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					owner := e.payerOwner
+					if id == 2 {
+						owner = e.payeeOwner
+					}
+					return &models.Account{ID: id, UserID: owner, Currency: "USD", Status: models.AccountStatusActive}, nil
+				}
+				mock.CaptureHold = func(id int64, amount int64) (*models.Hold, *models.Transfer, error) {
+					captured = true
+					if amount != e.expectedAmount {
+						t.Errorf("expected to capture %d, but got %d", e.expectedAmount, amount)
+					}
+					if e.captureErr != nil {
+						return nil, nil, e.captureErr
+					}
+					transferID := int64(7)
+					h := &models.Hold{ID: id, AccountID: 1, ToAccountID: 2, Amount: 500, CapturedAmount: 200, Status: models.HoldStatusCaptured, TransferID: &transferID}
+					return h, &models.Transfer{ID: transferID, FromAccountID: 1, ToAccountID: 2, Amount: 200, Currency: "USD"}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/3/capture", strings.NewReader(e.body))
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "3")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.captureHoldHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if response.Result().StatusCode == http.StatusNotFound && captured {
+				t.Error("expected a hold the user can not see not to be captured")
+			}
+			if e.statusCode != http.StatusOK {
+				return
+			}
+			var body struct {
+				Hold     *models.Hold     `json:"hold"`
+				Transfer *models.Transfer `json:"transfer"`
+			}
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.Hold == nil || body.Transfer == nil || *body.Hold.TransferID != body.Transfer.ID {
+				t.Errorf("expected the captured hold and its transfer, but got %+v", body)
+			}
+		})
+	}
+}
+
+func Test_application_releaseHoldHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		payeeOwner int64
+		releaseErr error
+		statusCode int
+	}{
+		{"success", testUser.ID, nil, http.StatusOK},
+		{"payer", testAdmin.ID, nil, http.StatusForbidden},
+		{"already released", testUser.ID, store.ErrHoldNotActive, http.StatusConflict},
+		{"expired", testUser.ID, store.ErrHoldExpired, http.StatusConflict},
+	}
+	_getHold := mock.GetHold
+	_getAccountByID := mock.GetAccountByID
+	_releaseHold := mock.ReleaseHold
+	defer func() {
+		mock.GetHold = _getHold
+		mock.GetAccountByID = _getAccountByID
+		mock.ReleaseHold = _releaseHold
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			released := false
+			{
+				// mock calls to db
+				mock.GetHold = func(id int64) (*models.Hold, error) {
+					return &models.Hold{ID: id, AccountID: 1, ToAccountID: 2, Amount: 500, Status: models.HoldStatusActive}, nil
+				}
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					// the hold is placed on account 1 of the test user for account 2
+					if id == 2 {
+						return &models.Account{ID: id, UserID: e.payeeOwner, Status: models.AccountStatusActive}, nil
+					}
+					return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusActive}, nil
+				}
+				mock.ReleaseHold = func(id int64) (*models.Hold, error) {
+					released = true
+					if e.releaseErr != nil {
+						return nil, e.releaseErr
+					}
+					return &models.Hold{ID: id, AccountID: 1, ToAccountID: 2, Amount: 500, Status: models.HoldStatusReleased}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/holds/3/release", nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "3")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.releaseHoldHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if e.statusCode == http.StatusForbidden && released {
+				t.Error("expected the payer not to release the hold")
+			}
+		})
+	}
+}
+
+func Test_application_accountBalanceHandler(t *testing.T) {
+	_getAccountByID := mock.GetAccountByID
+	_getAccountBalance := mock.GetAccountBalance
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetAccountBalance = _getAccountBalance
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Status: models.AccountStatusActive}, nil
+		}
+		mock.GetAccountBalance = func(accountID int64) (*models.Balance, error) {
+			return &models.Balance{AccountID: accountID, Currency: "USD", LedgerBalance: 1_000, Held: 600, OverdraftLimit: 100, Available: 500}, nil
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/balance", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	handler := http.HandlerFunc(app.accountBalanceHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Fatalf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+	var body struct {
+		Balance models.Balance `json:"balance"`
+	}
+	err := json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Balance.LedgerBalance != 1_000 || body.Balance.Held != 600 || body.Balance.Available != 500 {
+		t.Errorf("expected the ledger, held and available amounts, but got %+v", body.Balance)
+	}
+}
@@ -2,86 +2,117 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"log"
-	"net/http"
+	"errors"
+	"io/fs"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/Ruthvik10/simple_bank/internal/exchange"
 	"github.com/Ruthvik10/simple_bank/internal/logger"
+	"github.com/Ruthvik10/simple_bank/internal/ratelimit"
 	"github.com/Ruthvik10/simple_bank/internal/store"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-type config struct {
-	port string
-	env  string
-	db   struct {
-		dsn string
+type application struct {
+	cfg     config
+	logger  *logger.Logger
+	store   store.Store
+	metrics *metrics
+	// limiters are nil when rate limiting is disabled
+	limiters struct {
+		ip, read, money ratelimit.Limiter
 	}
+	// wg tracks the goroutines started through background
+	wg sync.WaitGroup
 }
 
-type application struct {
-	cfg    config
-	logger *logger.Logger
-	store  store.Store
-}
+func main() {
+	l := logger.New(os.Stdout, logger.LevelInfo)
 
-func init() {
+	// a .env file is a development convenience, deployments set the environment directly
 	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		l.PrintFatal(err, nil)
+	}
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
 	if err != nil {
-		log.Fatal(err)
+		l.PrintFatal(err, nil)
 	}
-}
-
-func main() {
-	cfg := config{
-		port: os.Getenv("PORT"),
-		env:  os.Getenv("ENV"),
-		db: struct{ dsn string }{
-			dsn: os.Getenv("DSN"),
-		},
+	app := &application{
+		cfg:     cfg,
+		logger:  l,
+		metrics: newMetrics(),
 	}
-	l := logger.New(os.Stdout, logger.LevelInfo)
-	app := application{
-		cfg:    cfg,
-		logger: l,
+	if cfg.limiter.enabled {
+		app.limiters.ip = ratelimit.NewMemory(cfg.limiter.ip.rps, cfg.limiter.ip.burst)
+		app.limiters.read = ratelimit.NewMemory(cfg.limiter.read.rps, cfg.limiter.read.burst)
+		app.limiters.money = ratelimit.NewMemory(cfg.limiter.money.rps, cfg.limiter.money.burst)
 	}
-	db, err := initDB(app.cfg.db.dsn)
+	db, err := initDB(app.cfg)
 	if err != nil {
 		app.logger.PrintFatal(err, nil)
 	}
 	defer db.Close()
-	app.store = store.NewStore(db)
-	app.logger.PrintInfo("database connection successful", nil)
-	app.logger.PrintInfo("starting server on port "+app.cfg.port, nil)
-	err = initServer(app.cfg.port, app.routes(), l)
-	if err != nil {
-		app.logger.PrintError(err, nil)
+
+	// cross currency transfers stay rejected unless explicitly enabled
+	var rates exchange.RateProvider
+	if app.cfg.transfers.crossCurrency {
+		rates, err = exchange.LoadFile(app.cfg.transfers.exchangeRatesFile)
+		if err != nil {
+			app.logger.PrintFatal(err, nil)
+		}
+		app.logger.PrintInfo("cross currency transfers enabled", map[string]any{"exchange_rates_file": app.cfg.transfers.exchangeRatesFile})
 	}
-}
+	app.store = store.NewStore(db, rates, app.cfg.db.timeouts)
+	app.logger.PrintInfo("database connection successful", map[string]any{
+		"max_open_conns": app.cfg.db.maxOpenConns,
+		"max_idle_conns": app.cfg.db.maxIdleConns,
+		"max_lifetime":   app.cfg.db.maxLifetime.String(),
+		"max_idle_time":  app.cfg.db.maxIdleTime.String(),
+	})
+
+	publishDebugVars(db.DB)
+	app.metrics.registerDB(db.DB, "simple_bank")
 
-func initServer(port string, handler http.Handler, logger *logger.Logger) error {
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", port),
-		Handler:      handler,
-		ReadTimeout:  10 * time.Second,
-		IdleTimeout:  time.Minute,
-		WriteTimeout: 30 * time.Second,
-		ErrorLog:     log.New(logger, "", 0),
+	if app.cfg.promoteAdmin != "" {
+		err = app.promoteAdmin(context.Background(), app.cfg.promoteAdmin)
+		if err != nil {
+			app.logger.PrintFatal(err, map[string]any{"email": app.cfg.promoteAdmin})
+		}
+		return
+	}
+	if app.cfg.reconcile.run {
+		consistent, err := app.reconcile(context.Background(), app.cfg.reconcile.fix)
+		if err != nil {
+			app.logger.PrintFatal(err, nil)
+		}
+		if !consistent {
+			db.Close()
+			os.Exit(1)
+		}
+		return
+	}
+	err = app.serve(db)
+	if err != nil {
+		app.logger.PrintFatal(err, nil)
 	}
-	return srv.ListenAndServe()
 }
 
-func initDB(dsn string) (*sqlx.DB, error) {
+func initDB(cfg config) (*sqlx.DB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	db, err := sqlx.ConnectContext(ctx, "postgres", dsn)
+	db, err := sqlx.ConnectContext(ctx, "postgres", cfg.db.dsn)
 	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(cfg.db.maxOpenConns)
+	db.SetMaxIdleConns(cfg.db.maxIdleConns)
+	db.SetConnMaxLifetime(cfg.db.maxLifetime)
+	db.SetConnMaxIdleTime(cfg.db.maxIdleTime)
 	return db, nil
 }
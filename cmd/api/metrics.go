@@ -0,0 +1,134 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Outcomes of a transfer as counted by the transfer metrics.
+const (
+	transferOutcomeSuccess             = "success"
+	transferOutcomeInsufficientBalance = "insufficient_balance"
+	transferOutcomeInvalidPayer        = "invalid_payer"
+	transferOutcomeInvalidPayee        = "invalid_payee"
+	transferOutcomeAccountUnavailable  = "account_unavailable"
+	transferOutcomeCurrencyMismatch    = "currency_mismatch"
+	transferOutcomeLimitExceeded       = "limit_exceeded"
+	transferOutcomeInvalidAmount       = "invalid_amount"
+	transferOutcomeError               = "error"
+)
+
+// metrics holds the Prometheus collectors of the application, served on /metrics.
+type metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	transfers        *prometheus.CounterVec
+	transferVolume   *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "simple_bank_http_requests_total",
+			Help: "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "simple_bank_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by method and route pattern.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "simple_bank_http_requests_in_flight",
+			Help: "Number of HTTP requests being served.",
+		}),
+		transfers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "simple_bank_transfers_total",
+			Help: "Number of transfers by payer currency and outcome.",
+		}, []string{"currency", "outcome"}),
+		transferVolume: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "simple_bank_transfer_volume_total",
+			Help: "Amount of the transfers in minor units of the payer currency, by currency and outcome.",
+		}, []string{"currency", "outcome"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.requestsInFlight, m.transfers, m.transferVolume,
+	)
+	return m
+}
+
+// registerDB adds the connection pool gauges of db, labelled with its name.
+func (m *metrics) registerDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// instrument counts and times the requests by the route pattern they matched, so that the
+// ids in the paths do not end up in the labels. Requests matching no route share one label.
+func (m *metrics) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.requestsInFlight.Inc()
+		defer m.requestsInFlight.Dec()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		m.requests.WithLabelValues(r.Method, route, strconv.Itoa(responseStatus(ww))).Inc()
+		m.requestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// observeTransfer counts a transfer of amount out of an account in currency that ended with
+// err. Replays of an idempotent transfer are not counted again.
+func (m *metrics) observeTransfer(currency string, amount int64, err error) {
+	if errors.Is(err, store.ErrDuplicateIdempotencyKey) {
+		return
+	}
+	if currency == "" {
+		currency = "unknown"
+	}
+	outcome := transferOutcome(err)
+	m.transfers.WithLabelValues(currency, outcome).Inc()
+	m.transferVolume.WithLabelValues(currency, outcome).Add(float64(amount))
+}
+
+func transferOutcome(err error) string {
+	switch {
+	case err == nil:
+		return transferOutcomeSuccess
+	case errors.Is(err, store.ErrInsufficientBalance):
+		return transferOutcomeInsufficientBalance
+	case errors.Is(err, store.ErrInvalidPayer):
+		return transferOutcomeInvalidPayer
+	case errors.Is(err, store.ErrInvalidPayee):
+		return transferOutcomeInvalidPayee
+	case errors.Is(err, store.ErrAccountFrozen), errors.Is(err, store.ErrAccountClosed):
+		return transferOutcomeAccountUnavailable
+	case errors.Is(err, store.ErrCurrencyMismatch), errors.Is(err, store.ErrUnsupportedCurrencyPair):
+		return transferOutcomeCurrencyMismatch
+	case errors.Is(err, store.ErrLimitExceeded):
+		return transferOutcomeLimitExceeded
+	case errors.Is(err, store.ErrInvalidAmount):
+		return transferOutcomeInvalidAmount
+	default:
+		return transferOutcomeError
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_metrics_instrument(t *testing.T) {
+	m := newMetrics()
+	r := chi.NewRouter()
+	r.Use(m.instrument)
+	r.Get("/api/v1/accounts/{id:^[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		if got := testutil.ToFloat64(m.requestsInFlight); got != 1 {
+			t.Errorf("expected 1 request in flight, but got %v", got)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	for _, path := range []string{"/api/v1/accounts/1", "/api/v1/accounts/2", "/api/v1/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route    string
+		status   int
+		expected float64
+	}{
+		{"/api/v1/accounts/{id:^[0-9]+}", http.StatusNotFound, 2},
+		{"unmatched", http.StatusNotFound, 1},
+	}
+	for _, e := range tests {
+		got := testutil.ToFloat64(m.requests.WithLabelValues(http.MethodGet, e.route, fmt.Sprint(e.status)))
+		if got != e.expected {
+			t.Errorf("%s: expected %v requests, but got %v", e.route, e.expected, got)
+		}
+	}
+	if got := testutil.ToFloat64(m.requestsInFlight); got != 0 {
+		t.Errorf("expected no requests in flight, but got %v", got)
+	}
+}
+
+func Test_metrics_observeTransfer(t *testing.T) {
+	m := newMetrics()
+	m.observeTransfer("USD", 500, nil)
+	m.observeTransfer("USD", 250, nil)
+	m.observeTransfer("USD", 100, fmt.Errorf("transfer: %w", store.ErrInsufficientBalance))
+	m.observeTransfer("", 100, store.ErrInvalidPayer)
+	m.observeTransfer("USD", 500, store.ErrDuplicateIdempotencyKey)
+
+	tests := []struct {
+		currency       string
+		outcome        string
+		expectedCount  float64
+		expectedVolume float64
+	}{
+		{"USD", transferOutcomeSuccess, 2, 750},
+		{"USD", transferOutcomeInsufficientBalance, 1, 100},
+		{"unknown", transferOutcomeInvalidPayer, 1, 100},
+	}
+	for _, e := range tests {
+		if got := testutil.ToFloat64(m.transfers.WithLabelValues(e.currency, e.outcome)); got != e.expectedCount {
+			t.Errorf("%s %s: expected %v transfers, but got %v", e.currency, e.outcome, e.expectedCount, got)
+		}
+		if got := testutil.ToFloat64(m.transferVolume.WithLabelValues(e.currency, e.outcome)); got != e.expectedVolume {
+			t.Errorf("%s %s: expected a volume of %v, but got %v", e.currency, e.outcome, e.expectedVolume, got)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/ratelimit"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// authenticate resolves the bearer token of the request into a user, requests without an
+// Authorization header carry the anonymous user.
+func (app *application) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			r = app.contextSetUser(r, models.AnonymousUser)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" || headerParts[1] == "" {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.store.User.GetForToken(r.Context(), models.ScopeAuthentication, headerParts[1])
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrRecordNotFound):
+				app.invalidAuthenticationTokenResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		r = app.contextSetUser(r, user)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) requireAuthenticatedUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if user.IsAnonymous() {
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) requirePermission(permission models.Permission, next http.HandlerFunc) http.HandlerFunc {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r)
+		if !user.HasPermission(permission) {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return app.requireAuthenticatedUser(http.HandlerFunc(fn)).ServeHTTP
+}
+
+// enableCORS lets the browsers of the trusted origins call the API, preflight requests of
+// those origins are answered here without reaching the routes.
+func (app *application) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			for _, trusted := range app.cfg.cors.trustedOrigins {
+				if origin != trusted {
+					continue
+				}
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+					w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, GET, POST, PUT, PATCH, DELETE")
+					w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key")
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimit limits the requests of every client with limiter, authenticated users are told
+// apart by their id and anonymous clients by their IP address. A nil limiter lets every
+// request through.
+func (app *application) rateLimit(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return app.limitBy(limiter, func(r *http.Request) string {
+		return rateLimitKey(app.contextGetUser(r), r)
+	})
+}
+
+// rateLimitIP limits the requests of every IP address with limiter. It runs before the
+// request is authenticated, so that floods of requests with bad credentials are throttled
+// before they cost a token lookup each. A nil limiter lets every request through.
+func (app *application) rateLimitIP(limiter ratelimit.Limiter) func(http.Handler) http.Handler {
+	return app.limitBy(limiter, func(r *http.Request) string {
+		return "ip:" + remoteIP(r)
+	})
+}
+
+func (app *application) limitBy(limiter ratelimit.Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, retryAfter := limiter.Allow(key(r))
+			if !ok {
+				app.rateLimitExceededResponse(w, r, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limiterIdleTimeout is how long the in-memory limiters keep a client that sends no requests.
+const limiterIdleTimeout = 3 * time.Minute
+
+// sweepLimiters forgets the idle clients of the in-memory limiters every minute, until ctx is
+// done.
+func (app *application) sweepLimiters(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, limiter := range []ratelimit.Limiter{app.limiters.ip, app.limiters.read, app.limiters.money} {
+			if m, ok := limiter.(*ratelimit.Memory); ok {
+				m.Sweep(limiterIdleTimeout)
+			}
+		}
+	}
+}
+
+func rateLimitKey(user *models.User, r *http.Request) string {
+	if !user.IsAnonymous() {
+		return fmt.Sprintf("user:%d", user.ID)
+	}
+	return "ip:" + remoteIP(r)
+}
+
+// remoteIP returns the IP address the request came from, without the port.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// requestIDRX matches the X-Request-ID values taken over from clients and proxies, anything
+// else is replaced so that it cannot be used to forge log lines.
+var requestIDRX = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID tags the request with the X-Request-ID it came with, or a new one, and echoes it
+// in the response so that clients can quote it.
+func (app *application) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDRX.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		r = app.contextSetRequestID(r, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms, an id is not worth a 500 anyway
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// logRequest writes one access log line per request once it has been served.
+func (app *application) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		app.logger.PrintInfo("request", map[string]any{
+			"request_id":  app.contextGetRequestID(r),
+			"method":      r.Method,
+			"route":       routePattern(r),
+			"status":      responseStatus(ww),
+			"bytes":       ww.BytesWritten(),
+			"latency":     time.Since(start).String(),
+			"remote_addr": r.RemoteAddr,
+		})
+	})
+}
+
+// recoverPanic turns a panicking handler into a 500 response, instead of the dropped
+// connection net/http leaves the client with.
+func (app *application) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				w.Header().Set("Connection", "close")
+				app.serverErrorResponse(w, r, fmt.Errorf("%v", err))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routePattern returns the pattern of the route that served r, so that logs and metrics are
+// not split by the ids in the paths. It is only known once the router has served r.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
+
+// responseStatus returns the status written through ww, handlers that write no header
+// answer with a 200.
+func responseStatus(ww middleware.WrapResponseWriter) int {
+	if ww.Status() == 0 {
+		return http.StatusOK
+	}
+	return ww.Status()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// reconciliationHandler reports every account whose balance disagrees with its ledger. A POST
+// additionally books correction entries for the reported drift.
+func (app *application) reconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	fix := r.Method == http.MethodPost
+	var actorID *int64
+	if fix {
+		user := app.contextGetUser(r)
+		actorID = &user.ID
+	}
+	discrepancies, err := app.store.Ledger.Reconcile(r.Context(), fix, actorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"discrepancies": discrepancies}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reconcile runs the reconciliation from the command line, logging every discrepancy found. It
+// reports whether the ledger is consistent once it is done.
+func (app *application) reconcile(ctx context.Context, fix bool) (bool, error) {
+	discrepancies, err := app.store.Ledger.Reconcile(ctx, fix, nil)
+	if err != nil {
+		return false, err
+	}
+	consistent := true
+	for _, d := range discrepancies {
+		app.logger.PrintInfo("ledger discrepancy", map[string]any{
+			"account_id":     d.AccountID,
+			"balance":        d.Balance,
+			"ledger_balance": d.LedgerBalance,
+			"drift":          d.Drift,
+			"corrected":      d.Corrected,
+		})
+		if d.Drift != 0 && !d.Corrected {
+			consistent = false
+		}
+	}
+	app.logger.PrintInfo("reconciliation finished", map[string]any{"discrepancies": len(discrepancies), "fix": fix})
+	return consistent, nil
+}
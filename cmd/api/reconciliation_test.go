@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+func Test_application_reconciliationHandler_report(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/reconciliation", nil)
+	req = app.contextSetUser(req, testAdmin)
+	_reconcile := mock.Reconcile
+	defer func() {
+		mock.Reconcile = _reconcile
+	}()
+	{
+		// mock calls to db
+		mock.Reconcile = func(fix bool, actorID *int64) ([]*models.Discrepancy, error) {
+			if fix {
+				t.Error("expected a GET to only report discrepancies")
+			}
+			return []*models.Discrepancy{{AccountID: 1, Balance: 1500, LedgerBalance: 1000, Drift: 500}}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.reconciliationHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+	if !strings.Contains(response.Body.String(), `"drift":500`) {
+		t.Errorf("expected the drift in the response, but got %s", response.Body.String())
+	}
+}
+
+func Test_application_reconciliationHandler_fix(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/reconciliation", nil)
+	req = app.contextSetUser(req, testAdmin)
+	_reconcile := mock.Reconcile
+	defer func() {
+		mock.Reconcile = _reconcile
+	}()
+	{
+		// mock calls to db
+		mock.Reconcile = func(fix bool, actorID *int64) ([]*models.Discrepancy, error) {
+			if !fix || actorID == nil || *actorID != testAdmin.ID {
+				t.Errorf("expected corrections booked by the admin, got fix %t, actor %v", fix, actorID)
+			}
+			return []*models.Discrepancy{{AccountID: 1, Balance: 1500, LedgerBalance: 1500, Drift: 500, Corrected: true}}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.reconciliationHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
+
+func Test_application_reconciliationHandler_not_permitted(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/reconciliation", nil)
+	req = app.contextSetUser(req, testUser)
+	handler := app.requirePermission(models.PermissionLedgerReconcile, app.reconciliationHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusForbidden {
+		t.Errorf("expected status code: %d, but got %d", http.StatusForbidden, response.Result().StatusCode)
+	}
+}
@@ -1,25 +1,80 @@
 package main
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
 func (app *application) routes() http.Handler {
 	r := chi.NewRouter()
+	// moneyLimit is the stricter limit of the routes moving money
+	moneyLimit := app.rateLimit(app.limiters.money)
+	r.Use(app.requestID)
+	r.Use(app.logRequest)
+	r.Use(app.metrics.instrument)
+	r.Use(app.recoverPanic)
+	r.Use(app.enableCORS)
+	r.Use(app.rateLimitIP(app.limiters.ip))
+	r.Use(app.authenticate)
+	r.Use(app.rateLimit(app.limiters.read))
+	r.Get("/livez", app.livezHandler)
+	r.Get("/readyz", app.readyzHandler)
+	r.Method(http.MethodGet, "/metrics", app.metrics.handler())
+	r.Get("/debug/vars", app.requirePermission(models.PermissionDebugRead, expvar.Handler().ServeHTTP))
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Get("/healthcheck", app.healthCheckHandler)
-		r.Route("/accounts", func(r chi.Router) {
-			r.Post("/", app.CreateAccountHandler)
-			r.Get("/{id:^[0-9]+}", app.getAccountByIDHandler)
-			r.Put("/", app.updateAccountHandler)
-			r.Patch("/{id:^[0-9]+}", app.updateBalanceHandler)
-			r.Get("/", app.listAccountsHandler)
-			r.Delete("/{id:^[0-9]+}", app.deleteAccountHandler)
-		})
-		r.Route("/transfers", func(r chi.Router) {
-			r.Post("/", app.createTransferHandler)
+		r.Post("/users", app.registerUserHandler)
+		r.Post("/tokens/authentication", app.createAuthenticationTokenHandler)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.requireAuthenticatedUser)
+			r.Patch("/users/{id:^[0-9]+}/role", app.requirePermission(models.PermissionUsersManage, app.updateUserRoleHandler))
+			r.Route("/accounts", func(r chi.Router) {
+				r.Post("/", app.CreateAccountHandler)
+				r.Get("/{id:^[0-9]+}", app.getAccountByIDHandler)
+				r.Put("/", app.updateAccountHandler)
+				r.Get("/", app.listAccountsHandler)
+				r.Delete("/{id:^[0-9]+}", app.requirePermission(models.PermissionAccountsDelete, app.closeAccountHandler))
+				r.Get("/{id:^[0-9]+}/transfers", app.listAccountTransfersHandler)
+				r.Get("/{id:^[0-9]+}/entries", app.accountStatementHandler)
+				r.With(moneyLimit).Post("/{id:^[0-9]+}/deposits", app.requirePermission(models.PermissionAccountsCash, app.depositHandler))
+				r.With(moneyLimit).Post("/{id:^[0-9]+}/withdrawals", app.requirePermission(models.PermissionAccountsCash, app.withdrawalHandler))
+				r.With(moneyLimit).Post("/{id:^[0-9]+}/adjustments", app.requirePermission(models.PermissionAccountsAdjust, app.adjustmentHandler))
+				r.Post("/{id:^[0-9]+}/freeze", app.requirePermission(models.PermissionAccountsFreeze, app.freezeAccountHandler))
+				r.Post("/{id:^[0-9]+}/unfreeze", app.requirePermission(models.PermissionAccountsFreeze, app.unfreezeAccountHandler))
+				r.Post("/{id:^[0-9]+}/close", app.requirePermission(models.PermissionAccountsDelete, app.closeAccountHandler))
+				r.Get("/{id:^[0-9]+}/limits", app.getAccountLimitsHandler)
+				r.Put("/{id:^[0-9]+}/limits", app.requirePermission(models.PermissionAccountsLimits, app.updateAccountLimitsHandler))
+				r.Put("/{id:^[0-9]+}/overdraft", app.requirePermission(models.PermissionAccountsOverdraft, app.setOverdraftLimitHandler))
+				r.Get("/{id:^[0-9]+}/holds", app.listAccountHoldsHandler)
+				r.Get("/{id:^[0-9]+}/balance", app.accountBalanceHandler)
+			})
+			r.Route("/transfers", func(r chi.Router) {
+				r.With(moneyLimit).Post("/", app.createTransferHandler)
+				r.Get("/{id:^[0-9]+}", app.getTransferByIDHandler)
+				r.With(moneyLimit).Post("/{id:^[0-9]+}/reverse", app.reverseTransferHandler)
+			})
+			r.Route("/holds", func(r chi.Router) {
+				r.With(moneyLimit).Post("/", app.placeHoldHandler)
+				r.Get("/{id:^[0-9]+}", app.getHoldHandler)
+				r.With(moneyLimit).Post("/{id:^[0-9]+}/capture", app.captureHoldHandler)
+				r.Post("/{id:^[0-9]+}/release", app.releaseHoldHandler)
+			})
+			r.Route("/scheduled-transfers", func(r chi.Router) {
+				r.Post("/", app.createScheduledTransferHandler)
+				r.Get("/", app.listScheduledTransfersHandler)
+				r.Get("/{id:^[0-9]+}", app.getScheduledTransferHandler)
+				r.Patch("/{id:^[0-9]+}", app.updateScheduledTransferHandler)
+				r.Delete("/{id:^[0-9]+}", app.deleteScheduledTransferHandler)
+				r.Get("/{id:^[0-9]+}/runs", app.listScheduledTransferRunsHandler)
+			})
+			r.Route("/admin", func(r chi.Router) {
+				r.Get("/reconciliation", app.requirePermission(models.PermissionLedgerReconcile, app.reconciliationHandler))
+				r.Post("/reconciliation", app.requirePermission(models.PermissionLedgerReconcile, app.reconciliationHandler))
+			})
 		})
 	})
 	return r
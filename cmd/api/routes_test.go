@@ -8,18 +8,52 @@ import (
 )
 
 func Test_application_routes(t *testing.T) {
-	var app application
+	app := application{metrics: newMetrics()}
 	var registered = []struct {
 		route  string
 		method string
 	}{
+		{"/livez", "GET"},
+		{"/readyz", "GET"},
+		{"/metrics", "GET"},
+		{"/debug/vars", "GET"},
 		{"/api/v1/healthcheck", "GET"},
+		{"/api/v1/users", "POST"},
+		{"/api/v1/tokens/authentication", "POST"},
+		{"/api/v1/users/{id:^[0-9]+}/role", "PATCH"},
 		{"/api/v1/accounts/", "POST"},
 		{"/api/v1/accounts/{id:^[0-9]+}", "GET"},
 		{"/api/v1/accounts/", "PUT"},
-		{"/api/v1/accounts/{id:^[0-9]+}", "PATCH"},
 		{"/api/v1/accounts/", "GET"},
 		{"/api/v1/accounts/{id:^[0-9]+}", "DELETE"},
+		{"/api/v1/accounts/{id:^[0-9]+}/transfers", "GET"},
+		{"/api/v1/accounts/{id:^[0-9]+}/entries", "GET"},
+		{"/api/v1/accounts/{id:^[0-9]+}/deposits", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/withdrawals", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/adjustments", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/freeze", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/unfreeze", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/close", "POST"},
+		{"/api/v1/accounts/{id:^[0-9]+}/limits", "GET"},
+		{"/api/v1/accounts/{id:^[0-9]+}/limits", "PUT"},
+		{"/api/v1/accounts/{id:^[0-9]+}/overdraft", "PUT"},
+		{"/api/v1/accounts/{id:^[0-9]+}/holds", "GET"},
+		{"/api/v1/accounts/{id:^[0-9]+}/balance", "GET"},
+		{"/api/v1/transfers/", "POST"},
+		{"/api/v1/transfers/{id:^[0-9]+}", "GET"},
+		{"/api/v1/transfers/{id:^[0-9]+}/reverse", "POST"},
+		{"/api/v1/holds/", "POST"},
+		{"/api/v1/holds/{id:^[0-9]+}", "GET"},
+		{"/api/v1/holds/{id:^[0-9]+}/capture", "POST"},
+		{"/api/v1/holds/{id:^[0-9]+}/release", "POST"},
+		{"/api/v1/scheduled-transfers/", "POST"},
+		{"/api/v1/scheduled-transfers/", "GET"},
+		{"/api/v1/scheduled-transfers/{id:^[0-9]+}", "GET"},
+		{"/api/v1/scheduled-transfers/{id:^[0-9]+}", "PATCH"},
+		{"/api/v1/scheduled-transfers/{id:^[0-9]+}", "DELETE"},
+		{"/api/v1/scheduled-transfers/{id:^[0-9]+}/runs", "GET"},
+		{"/api/v1/admin/reconciliation", "GET"},
+		{"/api/v1/admin/reconciliation", "POST"},
 	}
 	routes := app.routes()
 
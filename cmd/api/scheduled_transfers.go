@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/schedule"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+func (app *application) createScheduledTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		FromAccountID int64     `json:"from_account_id"`
+		ToAccountID   int64     `json:"to_account_id"`
+		Amount        int64     `json:"amount"`
+		Schedule      string    `json:"schedule"`
+		StartAt       time.Time `json:"start_at"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	user := app.contextGetUser(r)
+	st := &models.ScheduledTransfer{
+		UserID:        user.ID,
+		FromAccountID: input.FromAccountID,
+		ToAccountID:   input.ToAccountID,
+		Amount:        input.Amount,
+		Schedule:      input.Schedule,
+		StartAt:       input.StartAt,
+		NextRunAt:     &input.StartAt,
+		Active:        true,
+	}
+	v := validator.New()
+	models.ValidateScheduledTransfer(v, st)
+	v.Check(st.StartAt.After(time.Now()), "start_at", "must be in the future")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// customers can only schedule transfers out of their own accounts
+	payer, err := app.store.Account.Get(r.Context(), input.FromAccountID)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if payer == nil || !canAccessAccount(user, payer) {
+		app.badRequestErrorResponse(w, r, store.ErrInvalidPayer)
+		return
+	}
+
+	err = app.store.ScheduledTransfer.Create(r.Context(), st)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"scheduled_transfer": st}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) getScheduledTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	st, ok := app.getAccessibleScheduledTransfer(w, r, id)
+	if !ok {
+		return
+	}
+	err = app.writeJSON(w, envelope{"scheduled_transfer": st}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listScheduledTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	// customers only ever see their own scheduled transfers
+	user := app.contextGetUser(r)
+	userID := user.ID
+	if user.HasPermission(models.PermissionAccountsAccessAll) {
+		var err error
+		if userID, err = app.readInt64(r.URL.Query(), "user_id"); err != nil {
+			app.badRequestErrorResponse(w, r, err)
+			return
+		}
+	}
+	scheduled, err := app.store.ScheduledTransfer.List(r.Context(), userID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"scheduled_transfers": scheduled}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateScheduledTransferHandler changes the amount or the schedule of a scheduled transfer,
+// or pauses and resumes it. Resuming does not catch up on the runs missed while paused.
+func (app *application) updateScheduledTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Amount   *int64  `json:"amount"`
+		Schedule *string `json:"schedule"`
+		Active   *bool   `json:"active"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	st, ok := app.getAccessibleScheduledTransfer(w, r, id)
+	if !ok {
+		return
+	}
+
+	reschedule := false
+	if input.Amount != nil {
+		st.Amount = *input.Amount
+	}
+	if input.Schedule != nil && *input.Schedule != st.Schedule {
+		st.Schedule = *input.Schedule
+		reschedule = st.Active
+	}
+	if input.Active != nil && *input.Active != st.Active {
+		st.Active = *input.Active
+		reschedule = st.Active
+	}
+	v := validator.New()
+	if models.ValidateScheduledTransfer(v, st); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	if reschedule {
+		rule, err := schedule.Parse(st.Schedule)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		next, ok := rule.Next(st.StartAt, time.Now())
+		if !ok {
+			v.AddError("active", "a one-off transfer can not run again")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		st.NextRunAt = &next
+	}
+
+	err = app.store.ScheduledTransfer.Update(r.Context(), st)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"scheduled_transfer": st}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteScheduledTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleScheduledTransfer(w, r, id); !ok {
+		return
+	}
+	err = app.store.ScheduledTransfer.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"message": "scheduled transfer successfully deleted"}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listScheduledTransferRunsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if _, ok := app.getAccessibleScheduledTransfer(w, r, id); !ok {
+		return
+	}
+	runs, err := app.store.ScheduledTransfer.ListRuns(r.Context(), id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"runs": runs}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// getAccessibleScheduledTransfer fetches the scheduled transfer if the authenticated user
+// created it or may operate on every account. The error response has already been written
+// when ok is false.
+func (app *application) getAccessibleScheduledTransfer(w http.ResponseWriter, r *http.Request, id int64) (st *models.ScheduledTransfer, ok bool) {
+	st, err := app.store.ScheduledTransfer.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return nil, false
+		default:
+			app.serverErrorResponse(w, r, err)
+			return nil, false
+		}
+	}
+	user := app.contextGetUser(r)
+	if st.UserID != user.ID && !user.HasPermission(models.PermissionAccountsAccessAll) {
+		app.notFoundRespose(w, r)
+		return nil, false
+	}
+	return st, true
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_createScheduledTransferHandler_success(t *testing.T) {
+	startAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	reqBody := fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 2, "amount": 1200, "schedule": "@monthly", "start_at": %q}`, startAt.Format(time.RFC3339))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduled-transfers/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createScheduledTransfer := mock.CreateScheduledTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateScheduledTransfer = _createScheduledTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CreateScheduledTransfer = func(st *models.ScheduledTransfer) error {
+			if st.UserID != testUser.ID || !st.Active || st.NextRunAt == nil || !st.NextRunAt.Equal(startAt) {
+				t.Errorf("unexpected scheduled transfer: %+v", st)
+			}
+			st.ID = 1
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.createScheduledTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createScheduledTransferHandler_failed_validation(t *testing.T) {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	tests := []struct {
+		name    string
+		reqBody string
+	}{
+		{"unknown schedule", fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 2, "amount": 100, "schedule": "sometimes", "start_at": %q}`, future)},
+		{"interval too short", fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 2, "amount": 100, "schedule": "@every 1s", "start_at": %q}`, future)},
+		{"start in the past", fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 2, "amount": 100, "start_at": %q}`, past)},
+		{"missing start", `{"from_account_id": 1, "to_account_id": 2, "amount": 100}`},
+		{"self transfer", fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 1, "amount": 100, "start_at": %q}`, future)},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduled-transfers/", strings.NewReader(e.reqBody))
+			req = authenticated(req)
+			handler := http.HandlerFunc(app.createScheduledTransferHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_createScheduledTransferHandler_payer_not_owned(t *testing.T) {
+	reqBody := fmt.Sprintf(`{"from_account_id": 1, "to_account_id": 2, "amount": 100, "start_at": %q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/scheduled-transfers/", strings.NewReader(reqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: 99}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.createScheduledTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getScheduledTransferHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		stUserID   int64
+		getErr     error
+		statusCode int
+	}{
+		{"own", testUser.ID, nil, http.StatusOK},
+		{"other users", 99, nil, http.StatusNotFound},
+		{"record not found", 0, store.ErrRecordNotFound, http.StatusNotFound},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/scheduled-transfers/1", nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			_getScheduledTransfer := mock.GetScheduledTransfer
+			defer func() {
+				mock.GetScheduledTransfer = _getScheduledTransfer
+			}()
+			{
+				// mock calls to db
+				mock.GetScheduledTransfer = func(id int64) (*models.ScheduledTransfer, error) {
+					if e.getErr != nil {
+						return nil, e.getErr
+					}
+					return &models.ScheduledTransfer{ID: id, UserID: e.stUserID}, nil
+				}
+			}
+			handler := http.HandlerFunc(app.getScheduledTransferHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_updateScheduledTransferHandler_resume(t *testing.T) {
+	startAt := time.Now().Add(-72 * time.Hour)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/scheduled-transfers/1", strings.NewReader(`{"active": true}`))
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getScheduledTransfer := mock.GetScheduledTransfer
+	_updateScheduledTransfer := mock.UpdateScheduledTransfer
+	defer func() {
+		mock.GetScheduledTransfer = _getScheduledTransfer
+		mock.UpdateScheduledTransfer = _updateScheduledTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetScheduledTransfer = func(id int64) (*models.ScheduledTransfer, error) {
+			return &models.ScheduledTransfer{
+				ID: id, UserID: testUser.ID, FromAccountID: 1, ToAccountID: 2, Amount: 100,
+				Schedule: "@daily", StartAt: startAt, NextRunAt: &startAt, Active: false,
+			}, nil
+		}
+		mock.UpdateScheduledTransfer = func(st *models.ScheduledTransfer) error {
+			if !st.Active || st.NextRunAt == nil || !st.NextRunAt.After(time.Now()) {
+				t.Errorf("expected the resumed transfer to run next in the future, but got %v", st.NextRunAt)
+			}
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.updateScheduledTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
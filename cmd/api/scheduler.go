@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/schedule"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+)
+
+// schedulerBatchSize caps the number of scheduled transfers executed per tick.
+const schedulerBatchSize = 100
+
+// runScheduler executes the scheduled transfers that are due every interval, until ctx is done.
+// A transfer that has started when ctx is done is still carried out and recorded.
+func (app *application) runScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		app.runDueTransfers(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *application) runDueTransfers(ctx context.Context, now time.Time) {
+	due, err := app.store.ScheduledTransfer.Due(ctx, now, schedulerBatchSize)
+	if err != nil {
+		app.logger.PrintError(err, map[string]any{"scheduler": "due"})
+		return
+	}
+	for _, st := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		err = app.executeScheduledTransfer(context.Background(), st, now)
+		if err != nil {
+			app.logger.PrintError(err, map[string]any{"scheduled_transfer_id": st.ID})
+		}
+	}
+}
+
+// executeScheduledTransfer runs the latest transfer of st due by now and records the outcome.
+// Earlier occurrences that were missed, while the scheduler was down, are skipped rather than
+// replayed one after the other. Every run carries an idempotency key derived from its due
+// time, so a run that is executed again, by another instance or after a crash before its
+// outcome was recorded, moves money only once. Transfers the store rejects, and transfers of
+// users who can no longer access the payer account, are recorded as failed runs, any other
+// error leaves the run due so that it is tried again on the next tick.
+func (app *application) executeScheduledTransfer(ctx context.Context, st *models.ScheduledTransfer, now time.Time) error {
+	rule, err := schedule.Parse(st.Schedule)
+	if err != nil {
+		return err
+	}
+	dueAt, skipped := latestDue(rule, st.StartAt, *st.NextRunAt, now)
+	if skipped > 0 {
+		app.logger.PrintInfo("scheduled transfer runs skipped", map[string]any{
+			"scheduled_transfer_id": st.ID,
+			"from":                  st.NextRunAt.Format(time.RFC3339),
+			"count":                 skipped,
+		})
+	}
+
+	transfer := &models.Transfer{
+		FromAccountID: st.FromAccountID,
+		ToAccountID:   st.ToAccountID,
+		Amount:        st.Amount,
+	}
+	hash, err := requestHash(transfer)
+	if err != nil {
+		return err
+	}
+	key := &models.IdempotencyKey{
+		Key:            fmt.Sprintf("scheduled:%d:%d", st.ID, dueAt.Unix()),
+		RequestHash:    hash,
+		ResponseStatus: http.StatusCreated,
+		RenderBody: func(t *models.Transfer) ([]byte, error) {
+			return marshalJSON(envelope{"transfer": t})
+		},
+	}
+
+	run := &models.ScheduledTransferRun{
+		ScheduledTransferID: st.ID,
+		DueAt:               dueAt,
+		Status:              models.ScheduledRunSucceeded,
+	}
+	payer, err := app.scheduledPayer(ctx, st)
+	if err == nil {
+		err = app.store.Transfer.CreateTransfer(ctx, transfer, key)
+	}
+	// replays of a run were counted when it first went through, and runs that failed to be
+	// carried out are counted once they are
+	if err == nil || isTransferRejection(err) {
+		currency := ""
+		if payer != nil {
+			currency = payer.Currency
+		}
+		app.metrics.observeTransfer(currency, transfer.Amount, err)
+	}
+	switch {
+	case err == nil:
+		run.TransferID = &transfer.ID
+	case errors.Is(err, store.ErrDuplicateIdempotencyKey):
+		// the transfer went through before, only its outcome was not recorded
+		run.TransferID, err = app.executedTransferID(ctx, key.Key)
+		if err != nil {
+			return err
+		}
+	case isTransferRejection(err):
+		run.Status = models.ScheduledRunFailed
+		run.Error = err.Error()
+	default:
+		return err
+	}
+
+	var next *time.Time
+	if n, ok := rule.Next(st.StartAt, dueAt); ok {
+		next = &n
+	}
+	err = app.store.ScheduledTransfer.RecordRun(ctx, run, next)
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("scheduled transfer executed", map[string]any{
+		"scheduled_transfer_id": st.ID,
+		"due_at":                dueAt.Format(time.RFC3339),
+		"status":                run.Status,
+		"error":                 run.Error,
+	})
+	return nil
+}
+
+// scheduledPayer fetches the payer account of st. It returns store.ErrInvalidPayer, along with
+// the account if there is one, when the user who scheduled st can no longer access it, for
+// instance after a change of role.
+func (app *application) scheduledPayer(ctx context.Context, st *models.ScheduledTransfer) (*models.Account, error) {
+	user, err := app.store.User.Get(ctx, st.UserID)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		return nil, err
+	}
+	payer, err := app.store.Account.Get(ctx, st.FromAccountID)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user == nil || payer == nil || !canAccessAccount(user, payer) {
+		return payer, store.ErrInvalidPayer
+	}
+	return payer, nil
+}
+
+// latestDue returns the latest occurrence of rule that is due by now, dueAt being the first
+// occurrence that has not run, and the number of occurrences before it that were missed.
+func latestDue(rule schedule.Rule, start, dueAt, now time.Time) (time.Time, int) {
+	skipped := 0
+	for {
+		next, ok := rule.Next(start, dueAt)
+		if !ok || next.After(now) {
+			return dueAt, skipped
+		}
+		dueAt = next
+		skipped++
+	}
+}
+
+// executedTransferID reads the id of the transfer recorded for an idempotency key.
+func (app *application) executedTransferID(ctx context.Context, key string) (*int64, error) {
+	recorded, err := app.store.Transfer.GetIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	var body struct {
+		Transfer models.Transfer `json:"transfer"`
+	}
+	err = json.Unmarshal(recorded.ResponseBody, &body)
+	if err != nil {
+		return nil, err
+	}
+	return &body.Transfer.ID, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newDueTransfer(schedule string) *models.ScheduledTransfer {
+	startAt := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	return &models.ScheduledTransfer{
+		ID: 7, UserID: testUser.ID, FromAccountID: 1, ToAccountID: 2, Amount: 1200,
+		Schedule: schedule, StartAt: startAt, NextRunAt: &startAt, Active: true,
+	}
+}
+
+func Test_application_executeScheduledTransfer(t *testing.T) {
+	startAt := time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		schedule    string
+		now         time.Time
+		payerOwner  int64
+		transferErr error
+		status      string
+		dueAt       time.Time
+		transferID  int64
+		next        *time.Time
+	}{
+		{"recurring", "@monthly", startAt, testUser.ID, nil, models.ScheduledRunSucceeded, startAt, 42, ptrTime(time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC))},
+		{"one-off", "", startAt, testUser.ID, nil, models.ScheduledRunSucceeded, startAt, 42, nil},
+		{"rejected", "@monthly", startAt, testUser.ID, store.ErrInsufficientBalance, models.ScheduledRunFailed, startAt, 0, ptrTime(time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC))},
+		{"executed before", "@monthly", startAt, testUser.ID, store.ErrDuplicateIdempotencyKey, models.ScheduledRunSucceeded, startAt, 41, ptrTime(time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC))},
+		{"missed runs", "@every 1h", startAt.Add(5*time.Hour + 30*time.Minute), testUser.ID, nil, models.ScheduledRunSucceeded, startAt.Add(5 * time.Hour), 42, ptrTime(startAt.Add(6 * time.Hour))},
+		{"payer no longer accessible", "@monthly", startAt, testAdmin.ID, nil, models.ScheduledRunFailed, startAt, 0, ptrTime(time.Date(2024, time.February, 1, 9, 0, 0, 0, time.UTC))},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			_getUserByID := mock.GetUserByID
+			_getAccountByID := mock.GetAccountByID
+			_createTransfer := mock.CreateTransfer
+			_getIdempotencyKey := mock.GetIdempotencyKey
+			_recordRun := mock.RecordScheduledTransferRun
+			defer func() {
+				mock.GetUserByID = _getUserByID
+				mock.GetAccountByID = _getAccountByID
+				mock.CreateTransfer = _createTransfer
+				mock.GetIdempotencyKey = _getIdempotencyKey
+				mock.RecordScheduledTransferRun = _recordRun
+			}()
+			recorded, transferred := false, false
+			{
+				// mock calls to db
+				mock.GetUserByID = func(id int64) (*models.User, error) {
+					return testUser, nil
+				}
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: e.payerOwner, Currency: "USD", Status: models.AccountStatusActive}, nil
+				}
+				mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+					transferred = true
+					if want := fmt.Sprintf("scheduled:7:%d", e.dueAt.Unix()); key == nil || key.Key != want {
+						t.Errorf("expected the idempotency key %s derived from the due time, but got %+v", want, key)
+					}
+					if e.transferErr != nil {
+						return e.transferErr
+					}
+					tr.ID = 42
+					return nil
+				}
+				mock.GetIdempotencyKey = func(key string) (*models.IdempotencyKey, error) {
+					return &models.IdempotencyKey{Key: key, ResponseBody: []byte(`{"transfer":{"id":41}}`)}, nil
+				}
+				mock.RecordScheduledTransferRun = func(run *models.ScheduledTransferRun, next *time.Time) error {
+					recorded = true
+					if run.Status != e.status {
+						t.Errorf("expected status %s, but got %s", e.status, run.Status)
+					}
+					if !run.DueAt.Equal(e.dueAt) {
+						t.Errorf("expected the run due at %v, but got %v", e.dueAt, run.DueAt)
+					}
+					if e.transferID == 0 && run.TransferID != nil || e.transferID != 0 && (run.TransferID == nil || *run.TransferID != e.transferID) {
+						t.Errorf("expected transfer %d, but got %v", e.transferID, run.TransferID)
+					}
+					if (next == nil) != (e.next == nil) || next != nil && !next.Equal(*e.next) {
+						t.Errorf("expected next run at %v, but got %v", e.next, next)
+					}
+					return nil
+				}
+			}
+			// the transfer is counted under the currency of the payer account, replays are not
+			// counted again
+			outcomeErr, observed := e.transferErr, 1.0
+			if e.payerOwner != testUser.ID {
+				outcomeErr = store.ErrInvalidPayer
+			}
+			if errors.Is(e.transferErr, store.ErrDuplicateIdempotencyKey) {
+				outcomeErr, observed = nil, 0
+			}
+			counter := app.metrics.transfers.WithLabelValues("USD", transferOutcome(outcomeErr))
+			before := testutil.ToFloat64(counter)
+			err := app.executeScheduledTransfer(context.Background(), newDueTransfer(e.schedule), e.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != observed {
+				t.Errorf("expected %v transfers counted, but got %v", observed, got)
+			}
+			if !recorded {
+				t.Error("expected the run to be recorded")
+			}
+			if e.payerOwner != testUser.ID && transferred {
+				t.Error("expected no transfer from an account the user can no longer access")
+			}
+		})
+	}
+}
+
+func Test_application_executeScheduledTransfer_transient_error(t *testing.T) {
+	_getUserByID := mock.GetUserByID
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	_recordRun := mock.RecordScheduledTransferRun
+	defer func() {
+		mock.GetUserByID = _getUserByID
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+		mock.RecordScheduledTransferRun = _recordRun
+	}()
+	{
+		// mock calls to db
+		mock.GetUserByID = func(id int64) (*models.User, error) {
+			return testUser, nil
+		}
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID, Currency: "USD", Status: models.AccountStatusActive}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			return errors.New("connection reset")
+		}
+		mock.RecordScheduledTransferRun = func(run *models.ScheduledTransferRun, next *time.Time) error {
+			t.Error("expected the run to stay due")
+			return nil
+		}
+	}
+	st := newDueTransfer("@monthly")
+	err := app.executeScheduledTransfer(context.Background(), st, *st.NextRunAt)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
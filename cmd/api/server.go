@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// serve runs the HTTP server and the background tasks until SIGINT or SIGTERM. Shutting down
+// stops accepting connections and waits for in-flight requests, then stops the background
+// tasks and waits for them, and finally closes db. The stages share the grace period, the
+// whole shutdown has to complete within it.
+func (app *application) serve(db *sqlx.DB) error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", app.cfg.port),
+		Handler:      app.routes(),
+		ReadTimeout:  10 * time.Second,
+		IdleTimeout:  time.Minute,
+		WriteTimeout: 30 * time.Second,
+		ErrorLog:     log.New(app.logger, "", 0),
+	}
+
+	ctx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if app.cfg.scheduler.interval > 0 {
+		app.background(func() {
+			app.runScheduler(ctx, app.cfg.scheduler.interval)
+		})
+		app.logger.PrintInfo("scheduler started", map[string]any{"interval": app.cfg.scheduler.interval.String()})
+	}
+	if app.cfg.holds.expiryInterval > 0 {
+		app.background(func() {
+			app.runHoldExpiry(ctx, app.cfg.holds.expiryInterval)
+		})
+	}
+	if app.cfg.limiter.enabled {
+		app.background(func() {
+			app.sweepLimiters(ctx)
+		})
+	}
+
+	shutdownError := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.PrintInfo("shutting down server", map[string]any{
+			"signal":       s.String(),
+			"grace_period": app.cfg.shutdownGracePeriod.String(),
+		})
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), app.cfg.shutdownGracePeriod)
+		defer cancel()
+
+		var errs []error
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("waiting for in-flight requests: %w", err))
+		}
+
+		app.logger.PrintInfo("completing background tasks", nil)
+		stopBackground()
+		if err := app.waitBackground(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("waiting for background tasks: %w", err))
+		}
+
+		app.logger.PrintInfo("closing database connections", nil)
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing database connections: %w", err))
+		}
+		shutdownError <- errors.Join(errs...)
+	}()
+
+	app.logger.PrintInfo("starting server", map[string]any{"addr": srv.Addr, "env": app.cfg.env})
+	err := srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	err = <-shutdownError
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("stopped server", map[string]any{"addr": srv.Addr})
+	return nil
+}
+
+// background runs fn in a goroutine that shutdown waits for. A panic in fn is logged instead
+// of taking the server down.
+func (app *application) background(fn func()) {
+	app.wg.Add(1)
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%v", err), nil)
+			}
+		}()
+		fn()
+	}()
+}
+
+// waitBackground waits for the background goroutines to return, or for ctx to be done.
+func (app *application) waitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		app.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_application_background(t *testing.T) {
+	ran := make(chan struct{})
+	app.background(func() {
+		close(ran)
+	})
+	app.background(func() {
+		panic("boom")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := app.waitBackground(ctx)
+	if err != nil {
+		t.Fatalf("expected the background tasks to finish, but got %v", err)
+	}
+	select {
+	case <-ran:
+	default:
+		t.Error("expected the background task to have run")
+	}
+}
+
+func Test_application_waitBackground_grace_period(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	app.background(func() {
+		<-release
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := app.waitBackground(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to give up after the grace period, but got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+)
+
+func registeredUser(t *testing.T, password string) *models.User {
+	user := &models.User{ID: 1, Name: "Ruthvik", Email: "ruthvik@example.com"}
+	if err := user.SetPassword(password); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func Test_application_createAuthenticationTokenHandler(t *testing.T) {
+	user := registeredUser(t, "pa55word1234")
+	tests := []struct {
+		name               string
+		reqBody            string
+		expectedStatusCode int
+	}{
+		{"valid credentials", `{"email": "ruthvik@example.com", "password": "pa55word1234"}`, http.StatusCreated},
+		{"wrong password", `{"email": "ruthvik@example.com", "password": "wrongpassword"}`, http.StatusUnauthorized},
+		{"unknown email", `{"email": "nobody@example.com", "password": "pa55word1234"}`, http.StatusUnauthorized},
+	}
+	_getUserByEmail := mock.GetUserByEmail
+	_insertToken := mock.InsertToken
+	defer func() {
+		mock.GetUserByEmail = _getUserByEmail
+		mock.InsertToken = _insertToken
+	}()
+	{
+		// mock calls to db
+		mock.GetUserByEmail = func(email string) (*models.User, error) {
+			if email != user.Email {
+				return nil, store.ErrRecordNotFound
+			}
+			return user, nil
+		}
+		mock.InsertToken = func(token *models.Token) error {
+			if token.UserID != user.ID || token.Scope != models.ScopeAuthentication {
+				t.Errorf("unexpected token: %+v", token)
+			}
+			return nil
+		}
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/tokens/authentication", strings.NewReader(e.reqBody))
+			handler := http.HandlerFunc(app.createAuthenticationTokenHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("%s: expected status %d, but got %d", e.name, e.expectedStatusCode, response.Result().StatusCode)
+			}
+		})
+	}
+}
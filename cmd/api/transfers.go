@@ -2,10 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
 )
 
 func (app *application) createTransferHandler(w http.ResponseWriter, r *http.Request) {
@@ -20,26 +22,342 @@ func (app *application) createTransferHandler(w http.ResponseWriter, r *http.Req
 		app.badRequestErrorResponse(w, r, err)
 		return
 	}
-
 	transfer := &models.Transfer{
 		FromAccountID: input.FromAccountID,
 		ToAccountID:   input.ToAccountID,
 		Amount:        input.Amount,
 	}
+	v := validator.New()
+	if models.ValidateTransfer(v, transfer); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	user := app.contextGetUser(r)
+
+	// customers can only send money out of their own accounts
+	payer, err := app.store.Account.Get(r.Context(), input.FromAccountID)
+	if err != nil && !errors.Is(err, store.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if payer == nil || !canAccessAccount(user, payer) {
+		currency := ""
+		if payer != nil {
+			currency = payer.Currency
+		}
+		app.metrics.observeTransfer(currency, input.Amount, store.ErrInvalidPayer)
+		app.badRequestErrorResponse(w, r, store.ErrInvalidPayer)
+		return
+	}
 
-	err = app.store.Transfer.CreateTransfer(transfer)
+	idempotencyKey, done := app.readIdempotencyKey(w, r, input)
+	if done {
+		return
+	}
+
+	err = app.store.Transfer.CreateTransfer(r.Context(), transfer, idempotencyKey)
+	app.metrics.observeTransfer(payer.Currency, input.Amount, err)
 	if err != nil {
 		switch {
-		case errors.Is(err, store.ErrInvalidPayer), errors.Is(err, store.ErrInvalidPayee), errors.Is(err, store.ErrInsufficientBalance):
+		case isTransferRejection(err):
+			app.badRequestErrorResponse(w, r, err)
+			return
+		case errors.Is(err, store.ErrDuplicateIdempotencyKey):
+			// a concurrent request with the same key won the race, answer with its response
+			if !app.replayIdempotentResponse(w, r, idempotencyKey.Key, idempotencyKey.RequestHash) {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	if idempotencyKey != nil {
+		app.writeRawJSON(w, idempotencyKey.ResponseBody, idempotencyKey.ResponseStatus, nil)
+		return
+	}
+	err = app.writeJSON(w, envelope{"transfer": transfer}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// isTransferRejection reports whether CreateTransfer refused the transfer itself, as opposed to
+// failing to carry it out.
+func isTransferRejection(err error) bool {
+	for _, rejection := range []error{
+		store.ErrInvalidPayer, store.ErrInvalidPayee, store.ErrInsufficientBalance,
+		store.ErrCurrencyMismatch, store.ErrUnsupportedCurrencyPair, store.ErrInvalidAmount,
+		store.ErrAccountFrozen, store.ErrAccountClosed, store.ErrLimitExceeded,
+	} {
+		if errors.Is(err, rejection) {
+			return true
+		}
+	}
+	return false
+}
+
+func (app *application) getTransferByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	transfer, err := app.store.Transfer.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	accessible, err := app.canAccessTransfer(r, transfer)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !accessible {
+		app.notFoundRespose(w, r)
+		return
+	}
+	err = app.writeJSON(w, envelope{"transfer": transfer}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// reverseTransferHandler sends the transfer, or the amount given of it, back to its payer.
+// Customers can only give back what they received, reversing other users' transfers takes
+// PermissionTransfersReverse.
+func (app *application) reverseTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Amount int64 `json:"amount"`
+	}
+	// the body is optional, an empty one reverses whatever is left of the transfer
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
 			app.badRequestErrorResponse(w, r, err)
 			return
+		}
+	}
+	v := validator.New()
+	if v.Check(input.Amount >= 0, "amount", "must not be negative"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	original, err := app.store.Transfer.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
 		default:
 			app.serverErrorResponse(w, r, err)
 			return
 		}
 	}
-	err = app.writeJSON(w, envelope{"message": "successfully transferred the ammount"}, http.StatusOK, nil)
+	accessible, err := app.canAccessTransfer(r, original)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !accessible {
+		app.notFoundRespose(w, r)
+		return
+	}
+	user := app.contextGetUser(r)
+	if !user.HasPermission(models.PermissionTransfersReverse) {
+		payee, err := app.store.Account.Get(r.Context(), original.ToAccountID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if payee.UserID != user.ID {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+
+	// the key covers the transfer reversed as well, so it can not be reused for another one
+	idempotencyKey, done := app.readIdempotencyKey(w, r, struct{ ID, Amount int64 }{id, input.Amount})
+	if done {
+		return
+	}
+	reversal, err := app.store.Transfer.Reverse(r.Context(), id, input.Amount, idempotencyKey)
+	if err == nil || isTransferRejection(err) {
+		amount := input.Amount
+		if reversal != nil {
+			amount = reversal.Amount
+		}
+		app.metrics.observeTransfer(original.ToCurrency, amount, err)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		case errors.Is(err, store.ErrTransferIsReversal), errors.Is(err, store.ErrTransferReversed):
+			app.conflictResponse(w, r, err)
+			return
+		case errors.Is(err, store.ErrReversalExceedsTransfer):
+			app.failedValidationResponse(w, r, map[string]string{"amount": err.Error()})
+			return
+		case isTransferRejection(err):
+			app.badRequestErrorResponse(w, r, err)
+			return
+		case errors.Is(err, store.ErrDuplicateIdempotencyKey):
+			// a concurrent request with the same key won the race, answer with its response
+			if !app.replayIdempotentResponse(w, r, idempotencyKey.Key, idempotencyKey.RequestHash) {
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	app.logger.PrintInfo("transfer reversed", map[string]any{
+		"transfer_id": id,
+		"reversal_id": reversal.ID,
+		"amount":      reversal.Amount,
+		"actor_id":    user.ID,
+	})
+	if idempotencyKey != nil {
+		app.writeRawJSON(w, idempotencyKey.ResponseBody, idempotencyKey.ResponseStatus, nil)
+		return
+	}
+	err = app.writeJSON(w, envelope{"transfer": reversal}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// canAccessTransfer reports whether the authenticated user may see the transfer, which
+// customers only can when they hold the payer or the payee account.
+func (app *application) canAccessTransfer(r *http.Request, t *models.Transfer) (bool, error) {
+	user := app.contextGetUser(r)
+	if user.HasPermission(models.PermissionAccountsAccessAll) {
+		return true, nil
+	}
+	for _, id := range []int64{t.FromAccountID, t.ToAccountID} {
+		acc, err := app.store.Account.Get(r.Context(), id)
+		if err != nil {
+			return false, err
+		}
+		if acc.UserID == user.ID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (app *application) listAccountTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	qs := r.URL.Query()
+	var filter models.TransferFilter
+	filter.Direction = qs.Get("direction")
+	if !validator.PermittedValue(filter.Direction, "", models.TransferDirectionSent, models.TransferDirectionReceived) {
+		app.badRequestErrorResponse(w, r, errors.New("direction must be either sent or received"))
+		return
+	}
+	if filter.Since, err = app.readTime(qs, "from"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	if filter.Until, err = app.readTime(qs, "to"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	if filter.MinAmount, err = app.readInt64(qs, "min_amount"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	if filter.MaxAmount, err = app.readInt64(qs, "max_amount"); err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+
+	if _, ok := app.getAccessibleAccount(w, r, id); !ok {
+		return
+	}
+	transfers, err := app.store.Transfer.ListForAccount(r.Context(), id, filter)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"transfers": transfers}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readIdempotencyKey prepares the Idempotency-Key of the request, when it carries one, for a
+// transfer created from input. done reports that a response has already been written, which
+// is either an error or the recorded response of an earlier request with the same key.
+func (app *application) readIdempotencyKey(w http.ResponseWriter, r *http.Request, input any) (idempotencyKey *models.IdempotencyKey, done bool) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return nil, false
+	}
+	if len(key) > 200 {
+		app.badRequestErrorResponse(w, r, errors.New("the Idempotency-Key header must not be more than 200 bytes long"))
+		return nil, true
+	}
+	// keys are only unique per user
+	key = fmt.Sprintf("user:%d:%s", app.contextGetUser(r).ID, key)
+	hash, err := requestHash(input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return nil, true
+	}
+	if app.replayIdempotentResponse(w, r, key, hash) {
+		return nil, true
+	}
+	return &models.IdempotencyKey{
+		Key:            key,
+		RequestHash:    hash,
+		ResponseStatus: http.StatusCreated,
+		RenderBody: func(t *models.Transfer) ([]byte, error) {
+			return marshalJSON(envelope{"transfer": t})
+		},
+	}, false
+}
+
+// replayIdempotentResponse writes the recorded response for key if it exists and reports
+// whether a response has been written. Reusing a key with a different payload is rejected.
+func (app *application) replayIdempotentResponse(w http.ResponseWriter, r *http.Request, key, hash string) bool {
+	idempotencyKey, err := app.store.Transfer.GetIdempotencyKey(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			return false
+		default:
+			app.serverErrorResponse(w, r, err)
+			return true
+		}
+	}
+	if idempotencyKey.RequestHash != hash {
+		app.idempotencyKeyConflictResponse(w, r)
+		return true
 	}
+	headers := make(http.Header)
+	headers.Set("Idempotent-Replayed", "true")
+	app.writeRawJSON(w, idempotencyKey.ResponseBody, idempotencyKey.ResponseStatus, headers)
+	return true
 }
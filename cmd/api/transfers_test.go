@@ -0,0 +1,634 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+var transferReqBody = `{
+	"from_account_id": 1,
+	"to_account_id": 2,
+	"amount": 100
+}`
+
+func transferReqHash(t *testing.T) string {
+	hash, err := requestHash(struct {
+		FromAccountID int64 `json:"from_account_id"`
+		ToAccountID   int64 `json:"to_account_id"`
+		Amount        int64 `json:"amount"`
+	}{1, 2, 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func Test_application_createTransferHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			if key != nil {
+				t.Errorf("expected no idempotency key, but got %q", key.Key)
+			}
+			tr.ID = 1
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createTransferHandler_insufficient_balance(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			return store.ErrInsufficientBalance
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createTransferHandler_cross_currency_rejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"cross currency disabled", store.ErrCurrencyMismatch},
+		{"no exchange rate", fmt.Errorf("%w: USD to XYZ", store.ErrUnsupportedCurrencyPair)},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+			req = authenticated(req)
+			_getAccountByID := mock.GetAccountByID
+			_createTransfer := mock.CreateTransfer
+			defer func() {
+				mock.GetAccountByID = _getAccountByID
+				mock.CreateTransfer = _createTransfer
+			}()
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					return &models.Account{ID: id, UserID: testUser.ID}, nil
+				}
+				mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+					return e.err
+				}
+			}
+			handler := http.HandlerFunc(app.createTransferHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_createTransferHandler_database_error(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			return errors.New("error")
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createTransferHandler_records_idempotency_key(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	req.Header.Set("Idempotency-Key", "key-1")
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	_getIdempotencyKey := mock.GetIdempotencyKey
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+		mock.GetIdempotencyKey = _getIdempotencyKey
+	}()
+	var recorded *models.IdempotencyKey
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetIdempotencyKey = func(key string) (*models.IdempotencyKey, error) {
+			return nil, store.ErrRecordNotFound
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			tr.ID = 1
+			recorded = key
+			var err error
+			key.ResponseBody, err = key.RenderBody(tr)
+			return err
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+	if recorded == nil {
+		t.Fatal("expected the idempotency key to be passed to the store")
+	}
+	if recorded.Key != "user:1:key-1" || recorded.RequestHash != transferReqHash(t) {
+		t.Errorf("unexpected idempotency key recorded: %+v", recorded)
+	}
+	if response.Body.String() != string(recorded.ResponseBody) {
+		t.Errorf("expected response body %q, but got %q", recorded.ResponseBody, response.Body.String())
+	}
+}
+
+func Test_application_createTransferHandler_idempotent_replay(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	req.Header.Set("Idempotency-Key", "key-1")
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	_getIdempotencyKey := mock.GetIdempotencyKey
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+		mock.GetIdempotencyKey = _getIdempotencyKey
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetIdempotencyKey = func(key string) (*models.IdempotencyKey, error) {
+			return &models.IdempotencyKey{
+				Key:            key,
+				RequestHash:    transferReqHash(t),
+				ResponseStatus: http.StatusCreated,
+				ResponseBody:   []byte(`{"transfer":{"id":1}}`),
+			}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			t.Error("expected the transfer not to be executed again")
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+	if response.Body.String() != `{"transfer":{"id":1}}` {
+		t.Errorf("expected the original response body, but got %q", response.Body.String())
+	}
+}
+
+func Test_application_createTransferHandler_idempotency_key_conflict(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	req.Header.Set("Idempotency-Key", "key-1")
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	_getIdempotencyKey := mock.GetIdempotencyKey
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+		mock.GetIdempotencyKey = _getIdempotencyKey
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetIdempotencyKey = func(key string) (*models.IdempotencyKey, error) {
+			return &models.IdempotencyKey{
+				Key:            key,
+				RequestHash:    "a different payload",
+				ResponseStatus: http.StatusCreated,
+				ResponseBody:   []byte(`{"transfer":{"id":1}}`),
+			}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			t.Error("expected the transfer not to be executed")
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getTransferByIDHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transfers/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getTransfer := mock.GetTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetTransfer = _getTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.GetTransfer = func(id int64) (*models.Transfer, error) {
+			toReturn := &models.Transfer{
+				ID:            1,
+				FromAccountID: 1,
+				ToAccountID:   2,
+				Amount:        100,
+				CreatedAt:     time.Now(),
+			}
+			return toReturn, nil
+		}
+	}
+	handler := http.HandlerFunc(app.getTransferByIDHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getTransferByIDHandler_no_records_found(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transfers/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "10")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getTransfer := mock.GetTransfer
+	defer func() {
+		mock.GetTransfer = _getTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetTransfer = func(id int64) (*models.Transfer, error) {
+			return nil, store.ErrRecordNotFound
+		}
+	}
+	handler := http.HandlerFunc(app.getTransferByIDHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
+func Test_application_listAccountTransfersHandler_success(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/transfers?direction=sent&from=2023-01-01&min_amount=10&max_amount=500", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_listAccountTransfers := mock.ListAccountTransfers
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.ListAccountTransfers = _listAccountTransfers
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.ListAccountTransfers = func(accountID int64, filter models.TransferFilter) ([]*models.Transfer, error) {
+			if filter.Direction != models.TransferDirectionSent || filter.Since == nil || filter.Until != nil ||
+				filter.MinAmount != 10 || filter.MaxAmount != 500 {
+				t.Errorf("unexpected filter: %+v", filter)
+			}
+			return []*models.Transfer{}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.listAccountTransfersHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusOK {
+		t.Errorf("expected status code: %d, but got %d", http.StatusOK, response.Result().StatusCode)
+	}
+}
+
+func Test_application_listAccountTransfersHandler_bad_filter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"unknown direction", "direction=sideways"},
+		{"malformed date", "from=yesterday"},
+		{"non numeric amount", "min_amount=ten"},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/1/transfers?"+e.query, nil)
+			req = authenticated(req)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.listAccountTransfersHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_listAccountTransfersHandler_account_not_found(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/10/transfers", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "10")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return nil, store.ErrRecordNotFound
+		}
+	}
+	handler := http.HandlerFunc(app.listAccountTransfersHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createTransferHandler_payer_not_owned(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID + 1}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			t.Error("expected money not to be moved out of another user's account")
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+}
+
+func Test_application_getTransferByIDHandler_other_users_transfer(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/transfers/", nil)
+	req = authenticated(req)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "1")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	_getAccountByID := mock.GetAccountByID
+	_getTransfer := mock.GetTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetTransfer = _getTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID + 1}, nil
+		}
+		mock.GetTransfer = func(id int64) (*models.Transfer, error) {
+			return &models.Transfer{ID: id, FromAccountID: 3, ToAccountID: 4, Amount: 100}, nil
+		}
+	}
+	handler := http.HandlerFunc(app.getTransferByIDHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected status code: %d, but got %d", http.StatusNotFound, response.Result().StatusCode)
+	}
+}
+
+func Test_application_createTransferHandler_failed_validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		reqBody string
+	}{
+		{"self transfer", `{"from_account_id": 1, "to_account_id": 1, "amount": 100}`},
+		{"zero amount", `{"from_account_id": 1, "to_account_id": 2, "amount": 0}`},
+		{"negative amount", `{"from_account_id": 1, "to_account_id": 2, "amount": -100}`},
+		{"missing payee", `{"from_account_id": 1, "amount": 100}`},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(e.reqBody))
+			req = authenticated(req)
+			_createTransfer := mock.CreateTransfer
+			defer func() {
+				mock.CreateTransfer = _createTransfer
+			}()
+			{
+				// mock calls to db
+				mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+					t.Error("expected the transfer to be rejected before reaching the store")
+					return nil
+				}
+			}
+			handler := http.HandlerFunc(app.createTransferHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_createTransferHandler_limit_exceeded(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/", strings.NewReader(transferReqBody))
+	req = authenticated(req)
+	_getAccountByID := mock.GetAccountByID
+	_createTransfer := mock.CreateTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.CreateTransfer = _createTransfer
+	}()
+	{
+		// mock calls to db
+		mock.GetAccountByID = func(id int64) (*models.Account, error) {
+			return &models.Account{ID: id, UserID: testUser.ID}, nil
+		}
+		mock.CreateTransfer = func(tr *models.Transfer, key *models.IdempotencyKey) error {
+			return fmt.Errorf("%w: the daily maximum of 3 transfers has been reached", store.ErrLimitExceeded)
+		}
+	}
+	handler := http.HandlerFunc(app.createTransferHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status code: %d, but got %d", http.StatusBadRequest, response.Result().StatusCode)
+	}
+	if !strings.Contains(response.Body.String(), "daily maximum of 3 transfers") {
+		t.Errorf("expected the response to name the limit, but got %s", response.Body.String())
+	}
+}
+
+func Test_application_reverseTransferHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           *models.User
+		body           string
+		payerOwner     int64
+		payeeOwner     int64
+		reverseErr     error
+		statusCode     int
+		expectedAmount int64
+	}{
+		{"full refund by the payee", testUser, ``, testAdmin.ID, testUser.ID, nil, http.StatusCreated, 0},
+		{"partial refund by the payee", testUser, `{"amount": 40}`, testAdmin.ID, testUser.ID, nil, http.StatusCreated, 40},
+		{"reversed by an admin", testAdmin, `{"amount": 40}`, testUser.ID, testUser.ID, nil, http.StatusCreated, 40},
+		{"payer can not reverse", testUser, ``, testUser.ID, testAdmin.ID, nil, http.StatusForbidden, 0},
+		{"other users transfer", testUser, ``, testAdmin.ID, testAdmin.ID, nil, http.StatusNotFound, 0},
+		{"negative amount", testUser, `{"amount": -40}`, testAdmin.ID, testUser.ID, nil, http.StatusUnprocessableEntity, 0},
+		{"transfer id in the body", testUser, `{"transfer_id": 9, "amount": 40}`, testAdmin.ID, testUser.ID, nil, http.StatusBadRequest, 0},
+		{"more than left", testUser, `{"amount": 400}`, testAdmin.ID, testUser.ID, store.ErrReversalExceedsTransfer, http.StatusUnprocessableEntity, 400},
+		{"already reversed", testUser, ``, testAdmin.ID, testUser.ID, store.ErrTransferReversed, http.StatusConflict, 0},
+		{"reversal of a reversal", testUser, ``, testAdmin.ID, testUser.ID, store.ErrTransferIsReversal, http.StatusConflict, 0},
+		{"payee out of funds", testUser, ``, testAdmin.ID, testUser.ID, store.ErrInsufficientBalance, http.StatusBadRequest, 0},
+	}
+	_getAccountByID := mock.GetAccountByID
+	_getTransfer := mock.GetTransfer
+	_reverseTransfer := mock.ReverseTransfer
+	defer func() {
+		mock.GetAccountByID = _getAccountByID
+		mock.GetTransfer = _getTransfer
+		mock.ReverseTransfer = _reverseTransfer
+	}()
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			reversed := false
+			{
+				// mock calls to db
+				mock.GetAccountByID = func(id int64) (*models.Account, error) {
+					owner := e.payerOwner
+					if id == 2 {
+						owner = e.payeeOwner
+					}
+					return &models.Account{ID: id, UserID: owner, Currency: "USD", Status: models.AccountStatusActive}, nil
+				}
+				mock.GetTransfer = func(id int64) (*models.Transfer, error) {
+					return &models.Transfer{ID: id, FromAccountID: 1, ToAccountID: 2, Amount: 100, ToAmount: 100, Currency: "USD", ToCurrency: "USD"}, nil
+				}
+				mock.ReverseTransfer = func(id int64, amount int64, key *models.IdempotencyKey) (*models.Transfer, error) {
+					reversed = true
+					if amount != e.expectedAmount {
+						t.Errorf("expected to reverse %d, but got %d", e.expectedAmount, amount)
+					}
+					if e.reverseErr != nil {
+						return nil, e.reverseErr
+					}
+					if amount == 0 {
+						amount = 100
+					}
+					return &models.Transfer{ID: 9, FromAccountID: 2, ToAccountID: 1, Amount: amount, ToAmount: amount, ReversalOf: &id}, nil
+				}
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers/5/reverse", strings.NewReader(e.body))
+			req = app.contextSetUser(req, e.user)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "5")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.reverseTransferHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.statusCode {
+				t.Errorf("expected status code: %d, but got %d", e.statusCode, response.Result().StatusCode)
+			}
+			if (e.statusCode == http.StatusForbidden || e.statusCode == http.StatusNotFound) && reversed {
+				t.Error("expected the transfer not to be reversed")
+			}
+			if e.statusCode != http.StatusCreated {
+				return
+			}
+			var body struct {
+				Transfer models.Transfer `json:"transfer"`
+			}
+			err := json.NewDecoder(response.Body).Decode(&body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if body.Transfer.ReversalOf == nil || *body.Transfer.ReversalOf != 5 {
+				t.Errorf("expected the reversal to reference transfer 5, but got %+v", body.Transfer)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	user := &models.User{
+		Name:  input.Name,
+		Email: input.Email,
+	}
+	v := validator.New()
+	if models.ValidateUser(v, user, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	err = user.SetPassword(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.store.User.Insert(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateEmail):
+			v.AddError("email", "a user with this email address already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	err = app.writeJSON(w, envelope{"user": user}, http.StatusCreated, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.parseReqParam(r, "id")
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	var input struct {
+		Role string `json:"role"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestErrorResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(validator.PermittedValue(input.Role, models.Roles...), "role", "must be one of "+strings.Join(models.Roles, ", "))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.store.User.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	user.Role = input.Role
+	err = app.store.User.UpdateRole(r.Context(), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRecordNotFound):
+			app.notFoundRespose(w, r)
+			return
+		default:
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+	// the user signs in again under the new role, tokens issued before the change are revoked
+	err = app.store.Token.DeleteAllForUser(r.Context(), models.ScopeAuthentication, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, envelope{"user": user}, http.StatusOK, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// promoteAdmin makes the registered user with the email address an admin. It is run from the
+// command line to create the first admin, who can then change the roles of other users.
+func (app *application) promoteAdmin(ctx context.Context, email string) error {
+	user, err := app.store.User.GetByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	user.Role = models.RoleAdmin
+	err = app.store.User.UpdateRole(ctx, user)
+	if err != nil {
+		return err
+	}
+	err = app.store.Token.DeleteAllForUser(ctx, models.ScopeAuthentication, user.ID)
+	if err != nil {
+		return err
+	}
+	app.logger.PrintInfo("user promoted to admin", map[string]any{"user_id": user.ID, "email": user.Email})
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/Ruthvik10/simple_bank/internal/store"
+	"github.com/go-chi/chi/v5"
+)
+
+func Test_application_registerUserHandler_success(t *testing.T) {
+	reqBody := `{
+		"name": "Ruthvik",
+		"email": "ruthvik@example.com",
+		"password": "pa55word1234"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(reqBody))
+	_insertUser := mock.InsertUser
+	defer func() {
+		mock.InsertUser = _insertUser
+	}()
+	{
+		// mock calls to db
+		mock.InsertUser = func(u *models.User) error {
+			match, err := u.PasswordMatches("pa55word1234")
+			if err != nil || !match {
+				t.Error("expected the password hash to be stored")
+			}
+			u.ID = 1
+			return nil
+		}
+	}
+	handler := http.HandlerFunc(app.registerUserHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusCreated {
+		t.Errorf("expected status code: %d, but got %d", http.StatusCreated, response.Result().StatusCode)
+	}
+	if strings.Contains(response.Body.String(), "password") {
+		t.Errorf("expected the response not to contain the password, but got %s", response.Body.String())
+	}
+}
+
+func Test_application_registerUserHandler_bad_input(t *testing.T) {
+	tests := []struct {
+		name    string
+		reqBody string
+	}{
+		{"missing name", `{"email": "ruthvik@example.com", "password": "pa55word1234"}`},
+		{"invalid email", `{"name": "Ruthvik", "email": "ruthvik", "password": "pa55word1234"}`},
+		{"short password", `{"name": "Ruthvik", "email": "ruthvik@example.com", "password": "pass"}`},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(e.reqBody))
+			handler := http.HandlerFunc(app.registerUserHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+			}
+		})
+	}
+}
+
+func Test_application_registerUserHandler_duplicate_email(t *testing.T) {
+	reqBody := `{
+		"name": "Ruthvik",
+		"email": "ruthvik@example.com",
+		"password": "pa55word1234"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(reqBody))
+	_insertUser := mock.InsertUser
+	defer func() {
+		mock.InsertUser = _insertUser
+	}()
+	{
+		// mock calls to db
+		mock.InsertUser = func(u *models.User) error {
+			return store.ErrDuplicateEmail
+		}
+	}
+	handler := http.HandlerFunc(app.registerUserHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected status code: %d, but got %d", http.StatusUnprocessableEntity, response.Result().StatusCode)
+	}
+}
+
+func Test_application_registerUserHandler_database_error(t *testing.T) {
+	reqBody := `{
+		"name": "Ruthvik",
+		"email": "ruthvik@example.com",
+		"password": "pa55word1234"
+	}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(reqBody))
+	_insertUser := mock.InsertUser
+	defer func() {
+		mock.InsertUser = _insertUser
+	}()
+	{
+		// mock calls to db
+		mock.InsertUser = func(u *models.User) error {
+			return errors.New("error")
+		}
+	}
+	handler := http.HandlerFunc(app.registerUserHandler)
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, req)
+	if response.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected status code: %d, but got %d", http.StatusInternalServerError, response.Result().StatusCode)
+	}
+}
+
+func Test_application_updateUserRoleHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		userID             string
+		reqBody            string
+		expectedStatusCode int
+	}{
+		{"promote to teller", "1", `{"role": "teller"}`, http.StatusOK},
+		{"unknown role", "1", `{"role": "superuser"}`, http.StatusUnprocessableEntity},
+		{"unknown user", "10", `{"role": "teller"}`, http.StatusNotFound},
+	}
+	_getUserByID := mock.GetUserByID
+	_updateUserRole := mock.UpdateUserRole
+	_deleteAllTokensForUser := mock.DeleteAllTokensForUser
+	defer func() {
+		mock.GetUserByID = _getUserByID
+		mock.UpdateUserRole = _updateUserRole
+		mock.DeleteAllTokensForUser = _deleteAllTokensForUser
+	}()
+	var revoked int64
+	{
+		// mock calls to db
+		mock.GetUserByID = func(id int64) (*models.User, error) {
+			if id != 1 {
+				return nil, store.ErrRecordNotFound
+			}
+			return &models.User{ID: id, Role: models.RoleCustomer}, nil
+		}
+		mock.UpdateUserRole = func(u *models.User) error {
+			if u.Role != models.RoleTeller {
+				t.Errorf("expected role %s, but got %s", models.RoleTeller, u.Role)
+			}
+			return nil
+		}
+		mock.DeleteAllTokensForUser = func(scope string, userID int64) error {
+			if scope == models.ScopeAuthentication {
+				revoked = userID
+			}
+			return nil
+		}
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			revoked = 0
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/"+e.userID+"/role", strings.NewReader(e.reqBody))
+			req = app.contextSetUser(req, testAdmin)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", e.userID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler := http.HandlerFunc(app.updateUserRoleHandler)
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, req)
+			if response.Result().StatusCode != e.expectedStatusCode {
+				t.Errorf("%s: expected status %d, but got %d", e.name, e.expectedStatusCode, response.Result().StatusCode)
+			}
+			if changed := e.expectedStatusCode == http.StatusOK; changed != (revoked == 1) {
+				t.Errorf("%s: expected the tokens of the user to be revoked only when the role changed, but revoked those of %d", e.name, revoked)
+			}
+		})
+	}
+}
+
+func Test_application_promoteAdmin(t *testing.T) {
+	_getUserByEmail := mock.GetUserByEmail
+	_updateUserRole := mock.UpdateUserRole
+	_deleteAllTokensForUser := mock.DeleteAllTokensForUser
+	defer func() {
+		mock.GetUserByEmail = _getUserByEmail
+		mock.UpdateUserRole = _updateUserRole
+		mock.DeleteAllTokensForUser = _deleteAllTokensForUser
+	}()
+	var promoted *models.User
+	revoked := false
+	{
+		// mock calls to db
+		mock.GetUserByEmail = func(email string) (*models.User, error) {
+			if email != "admin@example.com" {
+				return nil, store.ErrRecordNotFound
+			}
+			return &models.User{ID: 5, Email: email, Role: models.RoleCustomer}, nil
+		}
+		mock.UpdateUserRole = func(u *models.User) error {
+			promoted = u
+			return nil
+		}
+		mock.DeleteAllTokensForUser = func(scope string, userID int64) error {
+			revoked = userID == 5
+			return nil
+		}
+	}
+	if err := app.promoteAdmin(context.Background(), "nobody@example.com"); !errors.Is(err, store.ErrRecordNotFound) {
+		t.Errorf("expected an unknown user not to be promoted, but got %v", err)
+	}
+	if err := app.promoteAdmin(context.Background(), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if promoted == nil || promoted.ID != 5 || promoted.Role != models.RoleAdmin || !revoked {
+		t.Errorf("expected user 5 to be made an admin and signed out, but got %+v", promoted)
+	}
+}
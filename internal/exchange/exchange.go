@@ -0,0 +1,110 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("exchange rate must be a positive decimal number")
+	ErrOverflow     = errors.New("converted amount overflows")
+)
+
+// RateProvider looks up the rate to convert an amount in currency from into currency to,
+// i.e. the number of units of to bought by one unit of from.
+type RateProvider interface {
+	Rate(from, to string) (*big.Rat, error)
+}
+
+// MemoryProvider is a RateProvider backed by a fixed set of rates held in memory.
+type MemoryProvider struct {
+	mu    sync.RWMutex
+	rates map[string]*big.Rat
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		rates: make(map[string]*big.Rat),
+	}
+}
+
+// LoadFile reads rates from a json file of the form {"USD": {"EUR": "0.92"}}.
+// Rates are not inverted, EUR to USD has to be listed separately.
+func LoadFile(path string) (*MemoryProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]map[string]string
+	err = json.Unmarshal(data, &rates)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rates file %s: %w", path, err)
+	}
+	p := NewMemoryProvider()
+	for from, quotes := range rates {
+		for to, rate := range quotes {
+			err = p.Set(from, to, rate)
+			if err != nil {
+				return nil, fmt.Errorf("exchange rates file %s: %s to %s: %w", path, from, to, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Set records the rate, given as a decimal string, to convert from into to.
+func (p *MemoryProvider) Set(from, to, rate string) error {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return ErrInvalidRate
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rates[from+"/"+to] = r
+	return nil
+}
+
+func (p *MemoryProvider) Rate(from, to string) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	r, ok := p.rates[from+"/"+to]
+	if !ok {
+		return nil, ErrRateNotFound
+	}
+	return new(big.Rat).Set(r), nil
+}
+
+// Convert returns amount multiplied by rate, rounded half away from zero to the nearest
+// minor unit.
+func Convert(amount int64, rate *big.Rat) (int64, error) {
+	product := new(big.Rat).Mul(big.NewRat(amount, 1), rate)
+	quo, rem := new(big.Int).QuoRem(product.Num(), product.Denom(), new(big.Int))
+	// round up when the remainder is at least half of the denominator
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(product.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(int64(product.Sign())))
+	}
+	if !quo.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return quo.Int64(), nil
+}
+
+// FormatRate formats rate as a decimal string suitable for a numeric column.
+func FormatRate(rate *big.Rat) string {
+	s := rate.FloatString(10)
+	for s[len(s)-1] == '0' {
+		s = s[:len(s)-1]
+	}
+	if s[len(s)-1] == '.' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package exchange
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		rate     string
+		expected int64
+	}{
+		{"identity", 1000, "1", 1000},
+		{"exact", 1000, "0.92", 920},
+		{"rounds to the nearest unit", 1001, "0.92", 921},
+		{"rounds half up", 5, "0.5", 3},
+		{"large rate", 250, "151.37", 37843},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			rate, _ := new(big.Rat).SetString(e.rate)
+			got, err := Convert(e.amount, rate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != e.expected {
+				t.Errorf("expected %d, but got %d", e.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvert_overflow(t *testing.T) {
+	_, err := Convert(1<<62, big.NewRat(4, 1))
+	if !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected %v, but got %v", ErrOverflow, err)
+	}
+}
+
+func TestMemoryProvider_Rate(t *testing.T) {
+	p := NewMemoryProvider()
+	if err := p.Set("USD", "EUR", "0.92"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Set("USD", "GBP", "-1"); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("expected %v, but got %v", ErrInvalidRate, err)
+	}
+
+	rate, err := p.Rate("USD", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FormatRate(rate) != "0.92" {
+		t.Errorf("expected rate 0.92, but got %s", FormatRate(rate))
+	}
+	rate, err = p.Rate("EUR", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if FormatRate(rate) != "1" {
+		t.Errorf("expected rate 1, but got %s", FormatRate(rate))
+	}
+	if _, err = p.Rate("EUR", "USD"); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("expected %v, but got %v", ErrRateNotFound, err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(path, []byte(`{"USD": {"EUR": "0.92", "INR": "82.5"}, "EUR": {"USD": "1.08"}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pair := range [][2]string{{"USD", "EUR"}, {"USD", "INR"}, {"EUR", "USD"}} {
+		if _, err := p.Rate(pair[0], pair[1]); err != nil {
+			t.Errorf("%s to %s: %v", pair[0], pair[1], err)
+		}
+	}
+
+	err = os.WriteFile(path, []byte(`{"USD": {"EUR": "abc"}}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadFile(path); !errors.Is(err, ErrInvalidRate) {
+		t.Errorf("expected %v, but got %v", ErrInvalidRate, err)
+	}
+}
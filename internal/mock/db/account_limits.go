@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockAccountLimitStore struct {
+}
+
+var GetAccountLimits = func(accountID int64) (*models.AccountLimits, error) {
+	return &models.AccountLimits{AccountID: accountID}, nil
+}
+
+var PutAccountLimits = func(l *models.AccountLimits) error {
+	return nil
+}
+
+func (mockStore MockAccountLimitStore) Get(ctx context.Context, accountID int64) (*models.AccountLimits, error) {
+	return GetAccountLimits(accountID)
+}
+
+func (mockStore MockAccountLimitStore) Put(ctx context.Context, l *models.AccountLimits) error {
+	return PutAccountLimits(l)
+}
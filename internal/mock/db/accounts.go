@@ -1,6 +1,10 @@
 package mock
 
-import "github.com/Ruthvik10/simple_bank/internal/models"
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
 
 type MockAccountStore struct {
 }
@@ -17,45 +21,69 @@ var UpdateAccount = func(acc *models.Account) error {
 	return nil
 }
 
-var UpdateBalance = func(acc *models.Account) error {
-	return nil
+var AdjustAccount = func(e *models.Entry) (*models.Account, error) {
+	return nil, nil
+}
+
+var ListAccounts = func(filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+	return nil, models.CursorMetadata{}, nil
 }
 
-var ListAccounts = func() ([]*models.Account, error) {
+var FreezeAccount = func(id int64) (*models.Account, error) {
 	return nil, nil
 }
 
-var DeleteAccount = func(id int64) error {
-	return nil
+var UnfreezeAccount = func(id int64) (*models.Account, error) {
+	return nil, nil
+}
+
+var CloseAccount = func(id int64) (*models.Account, error) {
+	return nil, nil
+}
+
+var SetAccountOverdraftLimit = func(id int64, limit int64) (*models.Account, error) {
+	return nil, nil
 }
 
 var GetAccountForUpdate = func(id int64) (*models.Account, error) {
 	return nil, nil
 }
 
-func (mockStore MockAccountStore) Create(acc *models.Account) error {
+func (mockStore MockAccountStore) Create(ctx context.Context, acc *models.Account) error {
 	return CreateAccount(acc)
 }
 
-func (mockStore MockAccountStore) Get(id int64) (*models.Account, error) {
+func (mockStore MockAccountStore) Get(ctx context.Context, id int64) (*models.Account, error) {
 	return GetAccountByID(id)
 }
-func (mockStore MockAccountStore) UpdateAccount(acc *models.Account) error {
+func (mockStore MockAccountStore) UpdateAccount(ctx context.Context, acc *models.Account) error {
 	return UpdateAccount(acc)
 }
 
-func (mockStore MockAccountStore) UpdateBalance(acc *models.Account) error {
-	return UpdateBalance(acc)
+func (mockStore MockAccountStore) Adjust(ctx context.Context, e *models.Entry) (*models.Account, error) {
+	return AdjustAccount(e)
+}
+
+func (mockStore MockAccountStore) List(ctx context.Context, filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+	return ListAccounts(filter)
+}
+
+func (mockStore MockAccountStore) Freeze(ctx context.Context, id int64) (*models.Account, error) {
+	return FreezeAccount(id)
+}
+
+func (mockStore MockAccountStore) Unfreeze(ctx context.Context, id int64) (*models.Account, error) {
+	return UnfreezeAccount(id)
 }
 
-func (mockStore MockAccountStore) List() ([]*models.Account, error) {
-	return ListAccounts()
+func (mockStore MockAccountStore) Close(ctx context.Context, id int64) (*models.Account, error) {
+	return CloseAccount(id)
 }
 
-func (mockStore MockAccountStore) Delete(id int64) error {
-	return DeleteAccount(id)
+func (mockStore MockAccountStore) SetOverdraftLimit(ctx context.Context, id int64, limit int64) (*models.Account, error) {
+	return SetAccountOverdraftLimit(id, limit)
 }
 
-func (mockStore MockAccountStore) GetForUpdate(id int64) (*models.Account, error) {
+func (mockStore MockAccountStore) GetForUpdate(ctx context.Context, id int64) (*models.Account, error) {
 	return GetAccountForUpdate(id)
 }
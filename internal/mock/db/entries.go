@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockEntryStore struct {
+}
+
+var CreateEntry = func(e *models.Entry) error {
+	return nil
+}
+
+var GetStatement = func(accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+	return nil, models.Metadata{}, nil
+}
+
+func (mockStore MockEntryStore) Create(ctx context.Context, e *models.Entry) error {
+	return CreateEntry(e)
+}
+
+func (mockStore MockEntryStore) Statement(ctx context.Context, accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+	return GetStatement(accountID, filter)
+}
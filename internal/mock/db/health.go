@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockHealthStore struct {
+}
+
+var Ping = func() error {
+	return nil
+}
+
+var MigrationStatus = func() (*models.MigrationStatus, error) {
+	return &models.MigrationStatus{Version: 1}, nil
+}
+
+func (mockStore MockHealthStore) Ping(ctx context.Context) error {
+	return Ping()
+}
+
+func (mockStore MockHealthStore) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	return MigrationStatus()
+}
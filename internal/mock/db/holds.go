@@ -0,0 +1,67 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockHoldStore struct {
+}
+
+var PlaceHold = func(h *models.Hold) error {
+	return nil
+}
+
+var GetHold = func(id int64) (*models.Hold, error) {
+	return nil, nil
+}
+
+var ListAccountHolds = func(accountID int64) ([]*models.Hold, error) {
+	return nil, nil
+}
+
+var CaptureHold = func(id int64, amount int64) (*models.Hold, *models.Transfer, error) {
+	return nil, nil, nil
+}
+
+var ReleaseHold = func(id int64) (*models.Hold, error) {
+	return nil, nil
+}
+
+var ExpireStaleHolds = func(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+var GetAccountBalance = func(accountID int64) (*models.Balance, error) {
+	return nil, nil
+}
+
+func (mockStore MockHoldStore) Place(ctx context.Context, h *models.Hold) error {
+	return PlaceHold(h)
+}
+
+func (mockStore MockHoldStore) Get(ctx context.Context, id int64) (*models.Hold, error) {
+	return GetHold(id)
+}
+
+func (mockStore MockHoldStore) ListForAccount(ctx context.Context, accountID int64) ([]*models.Hold, error) {
+	return ListAccountHolds(accountID)
+}
+
+func (mockStore MockHoldStore) Capture(ctx context.Context, id int64, amount int64) (*models.Hold, *models.Transfer, error) {
+	return CaptureHold(id, amount)
+}
+
+func (mockStore MockHoldStore) Release(ctx context.Context, id int64) (*models.Hold, error) {
+	return ReleaseHold(id)
+}
+
+func (mockStore MockHoldStore) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	return ExpireStaleHolds(now)
+}
+
+func (mockStore MockHoldStore) Balance(ctx context.Context, accountID int64) (*models.Balance, error) {
+	return GetAccountBalance(accountID)
+}
@@ -0,0 +1,18 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockLedgerStore struct {
+}
+
+var Reconcile = func(fix bool, actorID *int64) ([]*models.Discrepancy, error) {
+	return nil, nil
+}
+
+func (mockStore MockLedgerStore) Reconcile(ctx context.Context, fix bool, actorID *int64) ([]*models.Discrepancy, error) {
+	return Reconcile(fix, actorID)
+}
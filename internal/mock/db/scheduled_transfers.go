@@ -0,0 +1,75 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockScheduledTransferStore struct {
+}
+
+var CreateScheduledTransfer = func(st *models.ScheduledTransfer) error {
+	return nil
+}
+
+var GetScheduledTransfer = func(id int64) (*models.ScheduledTransfer, error) {
+	return nil, nil
+}
+
+var ListScheduledTransfers = func(userID int64) ([]*models.ScheduledTransfer, error) {
+	return nil, nil
+}
+
+var UpdateScheduledTransfer = func(st *models.ScheduledTransfer) error {
+	return nil
+}
+
+var DeleteScheduledTransfer = func(id int64) error {
+	return nil
+}
+
+var DueScheduledTransfers = func(now time.Time, limit int) ([]*models.ScheduledTransfer, error) {
+	return nil, nil
+}
+
+var RecordScheduledTransferRun = func(run *models.ScheduledTransferRun, next *time.Time) error {
+	return nil
+}
+
+var ListScheduledTransferRuns = func(id int64) ([]*models.ScheduledTransferRun, error) {
+	return nil, nil
+}
+
+func (mockStore MockScheduledTransferStore) Create(ctx context.Context, st *models.ScheduledTransfer) error {
+	return CreateScheduledTransfer(st)
+}
+
+func (mockStore MockScheduledTransferStore) Get(ctx context.Context, id int64) (*models.ScheduledTransfer, error) {
+	return GetScheduledTransfer(id)
+}
+
+func (mockStore MockScheduledTransferStore) List(ctx context.Context, userID int64) ([]*models.ScheduledTransfer, error) {
+	return ListScheduledTransfers(userID)
+}
+
+func (mockStore MockScheduledTransferStore) Update(ctx context.Context, st *models.ScheduledTransfer) error {
+	return UpdateScheduledTransfer(st)
+}
+
+func (mockStore MockScheduledTransferStore) Delete(ctx context.Context, id int64) error {
+	return DeleteScheduledTransfer(id)
+}
+
+func (mockStore MockScheduledTransferStore) Due(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledTransfer, error) {
+	return DueScheduledTransfers(now, limit)
+}
+
+func (mockStore MockScheduledTransferStore) RecordRun(ctx context.Context, run *models.ScheduledTransferRun, next *time.Time) error {
+	return RecordScheduledTransferRun(run, next)
+}
+
+func (mockStore MockScheduledTransferStore) ListRuns(ctx context.Context, id int64) ([]*models.ScheduledTransferRun, error) {
+	return ListScheduledTransferRuns(id)
+}
@@ -0,0 +1,26 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockTokenStore struct {
+}
+
+var InsertToken = func(t *models.Token) error {
+	return nil
+}
+
+var DeleteAllTokensForUser = func(scope string, userID int64) error {
+	return nil
+}
+
+func (mockStore MockTokenStore) Insert(ctx context.Context, t *models.Token) error {
+	return InsertToken(t)
+}
+
+func (mockStore MockTokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	return DeleteAllTokensForUser(scope, userID)
+}
@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockTransferStore struct {
+}
+
+var CreateTransfer = func(t *models.Transfer, key *models.IdempotencyKey) error {
+	return nil
+}
+
+var ReverseTransfer = func(id int64, amount int64, key *models.IdempotencyKey) (*models.Transfer, error) {
+	return nil, nil
+}
+
+var GetIdempotencyKey = func(key string) (*models.IdempotencyKey, error) {
+	return nil, nil
+}
+
+var GetTransfer = func(id int64) (*models.Transfer, error) {
+	return nil, nil
+}
+
+var ListAccountTransfers = func(accountID int64, filter models.TransferFilter) ([]*models.Transfer, error) {
+	return nil, nil
+}
+
+func (mockStore MockTransferStore) CreateTransfer(ctx context.Context, t *models.Transfer, key *models.IdempotencyKey) error {
+	return CreateTransfer(t, key)
+}
+
+func (mockStore MockTransferStore) Reverse(ctx context.Context, id int64, amount int64, key *models.IdempotencyKey) (*models.Transfer, error) {
+	return ReverseTransfer(id, amount, key)
+}
+
+func (mockStore MockTransferStore) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	return GetIdempotencyKey(key)
+}
+
+func (mockStore MockTransferStore) Get(ctx context.Context, id int64) (*models.Transfer, error) {
+	return GetTransfer(id)
+}
+
+func (mockStore MockTransferStore) ListForAccount(ctx context.Context, accountID int64, filter models.TransferFilter) ([]*models.Transfer, error) {
+	return ListAccountTransfers(accountID, filter)
+}
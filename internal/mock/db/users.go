@@ -0,0 +1,50 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+)
+
+type MockUserStore struct {
+}
+
+var GetUserByID = func(id int64) (*models.User, error) {
+	return nil, nil
+}
+
+var InsertUser = func(u *models.User) error {
+	return nil
+}
+
+var UpdateUserRole = func(u *models.User) error {
+	return nil
+}
+
+var GetUserByEmail = func(email string) (*models.User, error) {
+	return nil, nil
+}
+
+var GetUserForToken = func(scope, plaintext string) (*models.User, error) {
+	return nil, nil
+}
+
+func (mockStore MockUserStore) Get(ctx context.Context, id int64) (*models.User, error) {
+	return GetUserByID(id)
+}
+
+func (mockStore MockUserStore) Insert(ctx context.Context, u *models.User) error {
+	return InsertUser(u)
+}
+
+func (mockStore MockUserStore) UpdateRole(ctx context.Context, u *models.User) error {
+	return UpdateUserRole(u)
+}
+
+func (mockStore MockUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return GetUserByEmail(email)
+}
+
+func (mockStore MockUserStore) GetForToken(ctx context.Context, scope, plaintext string) (*models.User, error) {
+	return GetUserForToken(scope, plaintext)
+}
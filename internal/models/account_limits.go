@@ -0,0 +1,32 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+// AccountLimits caps the transfers sent from an account, in minor units of its currency. A nil
+// limit is no limit. The daily limits count the transfers sent since midnight UTC.
+type AccountLimits struct {
+	AccountID         int64      `json:"account_id" db:"account_id"`
+	MaxTransferAmount *int64     `json:"max_transfer_amount" db:"max_transfer_amount"`
+	MaxDailyAmount    *int64     `json:"max_daily_amount" db:"max_daily_amount"`
+	MaxDailyCount     *int       `json:"max_daily_count" db:"max_daily_count"`
+	UpdatedAt         *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+func ValidateAccountLimits(v *validator.Validator, l *AccountLimits) {
+	v.Check(l.MaxTransferAmount == nil || *l.MaxTransferAmount > 0, "max_transfer_amount", "must be greater than zero")
+	v.Check(l.MaxDailyAmount == nil || *l.MaxDailyAmount > 0, "max_daily_amount", "must be greater than zero")
+	v.Check(l.MaxDailyCount == nil || *l.MaxDailyCount > 0, "max_daily_count", "must be greater than zero")
+	if l.MaxTransferAmount != nil && l.MaxDailyAmount != nil {
+		v.Check(*l.MaxTransferAmount <= *l.MaxDailyAmount, "max_transfer_amount", "must not be more than max_daily_amount")
+	}
+}
+
+type AccountLimitStore interface {
+	Get(ctx context.Context, accountID int64) (*AccountLimits, error)
+	Put(ctx context.Context, l *AccountLimits) error
+}
@@ -1,23 +1,118 @@
 package models
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
 	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/validator"
 )
 
+// Account balances may go below zero down to -OverdraftLimit, OverdrawnSince is when the
+// balance last went negative and is nil while it is not. OverdrawnSeconds adds up the earlier
+// stretches spent overdrawn.
 type Account struct {
-	ID        int64     `json:"id" db:"id"`
-	Owner     string    `json:"owner" db:"owner"`
-	Balance   int64     `json:"balance" db:"balance"`
-	Currency  string    `json:"currency" db:"currency"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID               int64      `json:"id" db:"id"`
+	UserID           int64      `json:"user_id" db:"user_id"`
+	Owner            string     `json:"owner" db:"owner"`
+	Balance          int64      `json:"balance" db:"balance"`
+	Currency         string     `json:"currency" db:"currency"`
+	Status           string     `json:"status" db:"status"`
+	OverdraftLimit   int64      `json:"overdraft_limit" db:"overdraft_limit"`
+	OverdrawnSince   *time.Time `json:"overdrawn_since,omitempty" db:"overdrawn_since"`
+	OverdrawnSeconds int64      `json:"overdrawn_seconds" db:"overdrawn_seconds"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	ClosedAt         *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+}
+
+// OverdrawnFor returns the total time the account has spent overdrawn up to now, the current
+// stretch included.
+func (acc *Account) OverdrawnFor(now time.Time) time.Duration {
+	total := time.Duration(acc.OverdrawnSeconds) * time.Second
+	if acc.OverdrawnSince != nil {
+		total += now.Sub(*acc.OverdrawnSince)
+	}
+	return total
+}
+
+// MarshalJSON adds overdrawn_for_seconds, the total time the account has spent overdrawn as of
+// the response, to the stored fields.
+func (acc Account) MarshalJSON() ([]byte, error) {
+	// account drops the methods of Account, so marshalling it does not recurse
+	type account Account
+	return json.Marshal(struct {
+		account
+		OverdrawnForSeconds int64 `json:"overdrawn_for_seconds"`
+	}{account(acc), int64(acc.OverdrawnFor(time.Now()) / time.Second)})
+}
+
+// Available returns how much can be taken out of the account, overdraft included.
+func (acc *Account) Available() int64 {
+	return acc.Balance + acc.OverdraftLimit
+}
+
+// An account starts out active. Frozen accounts can neither send nor receive transfers until
+// they are unfrozen, closed accounts are kept for their history only.
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// Currencies holds the ISO 4217 codes accounts may be opened in.
+var Currencies = []string{
+	"AUD", "BRL", "CAD", "CHF", "CNY", "CZK", "DKK", "EUR", "GBP", "HKD", "HUF", "IDR", "ILS", "INR",
+	"JPY", "KRW", "MXN", "NOK", "NZD", "PHP", "PLN", "SEK", "SGD", "THB", "TRY", "USD", "ZAR",
+}
+
+func ValidateCurrency(v *validator.Validator, currency string) {
+	v.Check(currency != "", "currency", "must be provided")
+	v.Check(validator.PermittedValue(currency, Currencies...), "currency", "must be one of "+strings.Join(Currencies, ", "))
+}
+
+func ValidateAccount(v *validator.Validator, acc *Account) {
+	v.Check(strings.TrimSpace(acc.Owner) != "", "owner", "must be provided")
+	v.Check(len(acc.Owner) <= 100, "owner", "must not be more than 100 bytes long")
+	ValidateCurrency(v, acc.Currency)
+}
+
+func ValidateOverdraftLimit(v *validator.Validator, limit int64) {
+	v.Check(limit >= 0, "overdraft_limit", "must not be negative")
+}
+
+// AccountSortSafelist holds the sort values accepted when listing accounts, a leading
+// "-" sorts in descending order.
+var AccountSortSafelist = []string{"id", "-id", "balance", "-balance", "created_at", "-created_at"}
+
+// AccountFilter selects a page of accounts. After is the opaque cursor returned as
+// next_cursor by the previous page, it is only valid with the same Sort. A zero UserID
+// lists the accounts of every user. Closed accounts are left out unless IncludeClosed is set.
+type AccountFilter struct {
+	UserID        int64
+	Owner         string
+	Currency      string
+	IncludeClosed bool
+	Sort          string
+	After         string
+	Limit         int
+}
+
+type CursorMetadata struct {
+	NextCursor   string `json:"next_cursor,omitempty"`
+	Limit        int    `json:"limit"`
+	TotalRecords int    `json:"total_records"`
 }
 
 type AccountStore interface {
-	Get(int64) (*Account, error)
-	List() ([]*Account, error)
-	Create(*Account) error
-	UpdateAccount(*Account) error
-	UpdateBalance(*Account) error
-	Delete(id int64) error
-	GetForUpdate(int64) (*Account, error)
+	Get(ctx context.Context, id int64) (*Account, error)
+	List(ctx context.Context, filter AccountFilter) ([]*Account, CursorMetadata, error)
+	Create(ctx context.Context, acc *Account) error
+	UpdateAccount(ctx context.Context, acc *Account) error
+	Adjust(ctx context.Context, e *Entry) (*Account, error)
+	Freeze(ctx context.Context, id int64) (*Account, error)
+	Unfreeze(ctx context.Context, id int64) (*Account, error)
+	Close(ctx context.Context, id int64) (*Account, error)
+	SetOverdraftLimit(ctx context.Context, id int64, limit int64) (*Account, error)
+	GetForUpdate(ctx context.Context, id int64) (*Account, error)
 }
@@ -1,15 +1,61 @@
 package models
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
+// Entry is a signed movement on an account balance, every change of accounts.balance is
+// booked as an entry.
 type Entry struct {
 	ID        int64     `json:"id" db:"id"`
 	AccountID int64     `json:"account_id" db:"account_id"`
 	Amount    int64     `json:"amount" db:"amount"`
+	Reason    string    `json:"reason" db:"reason"`
+	ActorID   *int64    `json:"actor_id,omitempty" db:"actor_id"`
+	Note      string    `json:"note,omitempty" db:"note"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
+const (
+	EntryReasonOpening    = "opening"
+	EntryReasonTransfer   = "transfer"
+	EntryReasonReversal   = "reversal"
+	EntryReasonDeposit    = "deposit"
+	EntryReasonWithdrawal = "withdrawal"
+	EntryReasonCorrection = "correction"
+	EntryReasonFee        = "fee"
+	EntryReasonInterest   = "interest"
+)
+
+// AdjustmentReasons are the reasons accepted for manual adjustments.
+var AdjustmentReasons = []string{EntryReasonCorrection, EntryReasonFee, EntryReasonInterest}
+
+// StatementLine is an entry together with the account balance right after it was booked.
+type StatementLine struct {
+	Entry
+	RunningBalance int64 `json:"running_balance" db:"running_balance"`
+}
+
+// Statement lists the entries of an account booked within a period along with the
+// balance at the start and at the end of that period.
+type Statement struct {
+	AccountID      int64            `json:"account_id"`
+	From           *time.Time       `json:"from,omitempty"`
+	To             *time.Time       `json:"to,omitempty"`
+	OpeningBalance int64            `json:"opening_balance"`
+	ClosingBalance int64            `json:"closing_balance"`
+	Lines          []*StatementLine `json:"lines"`
+}
+
+// StatementFilter selects the period of a statement, nil bounds leave the period open.
+type StatementFilter struct {
+	Since *time.Time
+	Until *time.Time
+	Pagination
+}
+
 type EntryStore interface {
-	Create(*Entry) error
-	Get(int64) (*Entry, error)
+	Create(ctx context.Context, e *Entry) error
+	Statement(ctx context.Context, accountID int64, filter StatementFilter) (*Statement, Metadata, error)
 }
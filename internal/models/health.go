@@ -0,0 +1,15 @@
+package models
+
+import "context"
+
+// MigrationStatus is the schema version recorded by the migrate tool. A dirty version is a
+// migration that failed halfway and has to be fixed by hand.
+type MigrationStatus struct {
+	Version int64 `json:"version" db:"version"`
+	Dirty   bool  `json:"dirty" db:"dirty"`
+}
+
+type HealthStore interface {
+	Ping(ctx context.Context) error
+	MigrationStatus(ctx context.Context) (*MigrationStatus, error)
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+// Hold reserves Amount on an account for a later transfer to ToAccountID. While it is active
+// the amount can not be spent otherwise. Capturing it moves up to Amount in a transfer and
+// releases the rest, an active hold past ExpiresAt reserves nothing.
+type Hold struct {
+	ID             int64     `json:"id" db:"id"`
+	AccountID      int64     `json:"account_id" db:"account_id"`
+	ToAccountID    int64     `json:"to_account_id" db:"to_account_id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	Amount         int64     `json:"amount" db:"amount"`
+	CapturedAmount int64     `json:"captured_amount" db:"captured_amount"`
+	Status         string    `json:"status" db:"status"`
+	TransferID     *int64    `json:"transfer_id,omitempty" db:"transfer_id"`
+	ExpiresAt      time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+const (
+	// DefaultHoldDuration is how long a hold lasts unless it is given an expiry.
+	DefaultHoldDuration = 7 * 24 * time.Hour
+	// MaxHoldDuration is the longest a hold may last.
+	MaxHoldDuration = 30 * 24 * time.Hour
+)
+
+// Balance is the ledger balance of an account next to what can still be spent from it.
+type Balance struct {
+	AccountID      int64  `json:"account_id" db:"account_id"`
+	Currency       string `json:"currency" db:"currency"`
+	LedgerBalance  int64  `json:"ledger_balance" db:"ledger_balance"`
+	Held           int64  `json:"held" db:"held"`
+	OverdraftLimit int64  `json:"overdraft_limit" db:"overdraft_limit"`
+	Available      int64  `json:"available" db:"-"`
+}
+
+func ValidateHold(v *validator.Validator, h *Hold, now time.Time) {
+	v.Check(h.AccountID > 0, "account_id", "must be provided")
+	v.Check(h.ToAccountID > 0, "to_account_id", "must be provided")
+	v.Check(h.AccountID != h.ToAccountID, "to_account_id", "must be different from account_id")
+	v.Check(h.Amount > 0, "amount", "must be greater than zero")
+	v.Check(h.ExpiresAt.After(now), "expires_at", "must be in the future")
+	v.Check(!h.ExpiresAt.After(now.Add(MaxHoldDuration)), "expires_at", "must not be more than 30 days away")
+}
+
+type HoldStore interface {
+	Place(ctx context.Context, h *Hold) error
+	Get(ctx context.Context, id int64) (*Hold, error)
+	ListForAccount(ctx context.Context, accountID int64) ([]*Hold, error)
+	Capture(ctx context.Context, id int64, amount int64) (*Hold, *Transfer, error)
+	Release(ctx context.Context, id int64) (*Hold, error)
+	ExpireStale(ctx context.Context, now time.Time) (int64, error)
+	Balance(ctx context.Context, accountID int64) (*Balance, error)
+}
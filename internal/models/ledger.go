@@ -0,0 +1,16 @@
+package models
+
+import "context"
+
+// Discrepancy is an account whose balance disagrees with the sum of its entries.
+type Discrepancy struct {
+	AccountID     int64 `json:"account_id" db:"account_id"`
+	Balance       int64 `json:"balance" db:"balance"`
+	LedgerBalance int64 `json:"ledger_balance" db:"ledger_balance"`
+	Drift         int64 `json:"drift" db:"drift"`
+	Corrected     bool  `json:"corrected"`
+}
+
+type LedgerStore interface {
+	Reconcile(ctx context.Context, fix bool, actorID *int64) ([]*Discrepancy, error)
+}
@@ -0,0 +1,39 @@
+package models
+
+import "math"
+
+type Pagination struct {
+	Page     int
+	PageSize int
+}
+
+func (p Pagination) Limit() int {
+	return p.PageSize
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+type Metadata struct {
+	CurrentPage  int `json:"current_page,omitempty"`
+	PageSize     int `json:"page_size,omitempty"`
+	FirstPage    int `json:"first_page,omitempty"`
+	LastPage     int `json:"last_page,omitempty"`
+	TotalRecords int `json:"total_records,omitempty"`
+}
+
+// CalculateMetadata returns the pagination metadata for a page of totalRecords,
+// an empty Metadata is returned when there are no records.
+func CalculateMetadata(totalRecords int, p Pagination) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+	return Metadata{
+		CurrentPage:  p.Page,
+		PageSize:     p.PageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(p.PageSize))),
+		TotalRecords: totalRecords,
+	}
+}
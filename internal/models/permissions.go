@@ -0,0 +1,75 @@
+package models
+
+const (
+	RoleCustomer = "customer"
+	RoleTeller   = "teller"
+	RoleAdmin    = "admin"
+)
+
+var Roles = []string{RoleCustomer, RoleTeller, RoleAdmin}
+
+type Permission string
+
+const (
+	// PermissionAccountsAccessAll lets a user operate on accounts of other users.
+	PermissionAccountsAccessAll Permission = "accounts:access-all"
+	// PermissionAccountsCash lets a user book deposits and withdrawals.
+	PermissionAccountsCash Permission = "accounts:cash"
+	// PermissionAccountsAdjust lets a user book manual adjustments such as corrections and fees.
+	PermissionAccountsAdjust Permission = "accounts:adjust"
+	// PermissionAccountsOpeningBalance lets a user open accounts with a balance, booked as an
+	// opening entry. Everyone else opens accounts at zero and funds them through deposits.
+	PermissionAccountsOpeningBalance Permission = "accounts:opening-balance"
+	// PermissionAccountsCurrencyChange lets a user change the currency of an account.
+	PermissionAccountsCurrencyChange Permission = "accounts:currency-change"
+	// PermissionAccountsFreeze lets a user freeze and unfreeze accounts.
+	PermissionAccountsFreeze Permission = "accounts:freeze"
+	// PermissionAccountsLimits lets a user change the transfer limits of accounts.
+	PermissionAccountsLimits Permission = "accounts:limits"
+	// PermissionAccountsOverdraft lets a user grant and change overdraft limits.
+	PermissionAccountsOverdraft Permission = "accounts:overdraft"
+	// PermissionAccountsDelete lets a user delete accounts, which closes them.
+	PermissionAccountsDelete Permission = "accounts:delete"
+	// PermissionTransfersReverse lets a user reverse transfers received by other users.
+	PermissionTransfersReverse Permission = "transfers:reverse"
+	// PermissionDebugRead lets a user read runtime and database pool statistics.
+	PermissionDebugRead Permission = "debug:read"
+	// PermissionLedgerReconcile lets a user reconcile balances against the entries ledger.
+	PermissionLedgerReconcile Permission = "ledger:reconcile"
+	// PermissionUsersManage lets a user change the role of other users.
+	PermissionUsersManage Permission = "users:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleCustomer: {},
+	RoleTeller: {
+		PermissionAccountsAccessAll,
+		PermissionAccountsCash,
+		PermissionAccountsFreeze,
+		PermissionAccountsLimits,
+	},
+	RoleAdmin: {
+		PermissionAccountsAccessAll,
+		PermissionAccountsCash,
+		PermissionAccountsAdjust,
+		PermissionAccountsOpeningBalance,
+		PermissionAccountsCurrencyChange,
+		PermissionAccountsFreeze,
+		PermissionAccountsLimits,
+		PermissionAccountsOverdraft,
+		PermissionAccountsDelete,
+		PermissionTransfersReverse,
+		PermissionDebugRead,
+		PermissionLedgerReconcile,
+		PermissionUsersManage,
+	},
+}
+
+func (u *User) HasPermission(p Permission) bool {
+	for _, permission := range rolePermissions[u.Role] {
+		if permission == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/schedule"
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+// ScheduledTransfer is a standing order, it executes a transfer at StartAt and then as often
+// as its Schedule says. NextRunAt is nil once a one-off transfer has run.
+type ScheduledTransfer struct {
+	ID            int64      `json:"id" db:"id"`
+	UserID        int64      `json:"user_id" db:"user_id"`
+	FromAccountID int64      `json:"from_account_id" db:"from_account_id"`
+	ToAccountID   int64      `json:"to_account_id" db:"to_account_id"`
+	Amount        int64      `json:"amount" db:"amount"`
+	Schedule      string     `json:"schedule" db:"schedule"`
+	StartAt       time.Time  `json:"start_at" db:"start_at"`
+	NextRunAt     *time.Time `json:"next_run_at" db:"next_run_at"`
+	Active        bool       `json:"active" db:"active"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+const (
+	ScheduledRunSucceeded = "succeeded"
+	ScheduledRunFailed    = "failed"
+)
+
+// ScheduledTransferRun records the outcome of one execution of a scheduled transfer.
+type ScheduledTransferRun struct {
+	ID                  int64     `json:"id" db:"id"`
+	ScheduledTransferID int64     `json:"scheduled_transfer_id" db:"scheduled_transfer_id"`
+	DueAt               time.Time `json:"due_at" db:"due_at"`
+	Status              string    `json:"status" db:"status"`
+	TransferID          *int64    `json:"transfer_id" db:"transfer_id"`
+	Error               string    `json:"error" db:"error"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+}
+
+func ValidateScheduledTransfer(v *validator.Validator, st *ScheduledTransfer) {
+	ValidateTransfer(v, &Transfer{FromAccountID: st.FromAccountID, ToAccountID: st.ToAccountID, Amount: st.Amount})
+	_, err := schedule.Parse(st.Schedule)
+	v.Check(err == nil, "schedule", `must be empty, "@daily", "@weekly", "@monthly" or "@every <duration>" of at least a minute`)
+	v.Check(!st.StartAt.IsZero(), "start_at", "must be provided")
+}
+
+type ScheduledTransferStore interface {
+	Create(ctx context.Context, st *ScheduledTransfer) error
+	Get(ctx context.Context, id int64) (*ScheduledTransfer, error)
+	// List returns the scheduled transfers of the user, or of every user when userID is zero.
+	List(ctx context.Context, userID int64) ([]*ScheduledTransfer, error)
+	Update(ctx context.Context, st *ScheduledTransfer) error
+	Delete(ctx context.Context, id int64) error
+	// Due returns up to limit active scheduled transfers whose next run is not after now.
+	Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledTransfer, error)
+	// RecordRun stores the outcome of the run and moves the scheduled transfer on to next,
+	// nil when it has no further runs. Runs due before run.DueAt that have not run are
+	// skipped.
+	RecordRun(ctx context.Context, run *ScheduledTransferRun, next *time.Time) error
+	ListRuns(ctx context.Context, id int64) ([]*ScheduledTransferRun, error)
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"time"
+)
+
+const (
+	ScopeAuthentication = "authentication"
+)
+
+// Token is a bearer token issued to a user. Only the sha256 hash of the plaintext is
+// persisted, the plaintext is handed to the client once.
+type Token struct {
+	Plaintext string    `json:"token" db:"-"`
+	Hash      []byte    `json:"-" db:"hash"`
+	UserID    int64     `json:"-" db:"user_id"`
+	Expiry    time.Time `json:"expiry" db:"expiry"`
+	Scope     string    `json:"-" db:"scope"`
+}
+
+func GenerateToken(userID int64, ttl time.Duration, scope string) (*Token, error) {
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Now().Add(ttl),
+		Scope:  scope,
+	}
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	token.Hash = HashToken(token.Plaintext)
+	return token, nil
+}
+
+func HashToken(plaintext string) []byte {
+	hash := sha256.Sum256([]byte(plaintext))
+	return hash[:]
+}
+
+type TokenStore interface {
+	Insert(ctx context.Context, t *Token) error
+	DeleteAllForUser(ctx context.Context, scope string, userID int64) error
+}
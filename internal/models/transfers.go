@@ -1,14 +1,93 @@
 package models
 
-import "time"
+import (
+	"context"
+	"time"
 
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+)
+
+// Transfer moves Amount in the payer's Currency out of the payer's account and credits
+// ToAmount in the payee's ToCurrency, the two only differ for cross currency transfers.
+// A reversal is a transfer back from the payee to the payer of the transfer it reverses,
+// ReversedAmount adds up the reversals of a transfer in its ToCurrency.
 type Transfer struct {
-	FromAccountID int64     `json:"from_account_id"`
-	ToAccountID   int64     `json:"to_account_id"`
-	Amount        int64     `json:"amount"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64     `json:"id" db:"id"`
+	FromAccountID int64     `json:"from_account_id" db:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id" db:"to_account_id"`
+	Amount        int64     `json:"amount" db:"amount"`
+	Currency      string    `json:"currency" db:"currency"`
+	ToAmount      int64     `json:"to_amount" db:"to_amount"`
+	ToCurrency    string    `json:"to_currency" db:"to_currency"`
+	ExchangeRate  string    `json:"exchange_rate" db:"exchange_rate"`
+	ReversalOf    *int64    `json:"reversal_of,omitempty" db:"reversal_of"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+
+	ReversedAmount int64  `json:"reversed_amount" db:"reversed_amount"`
+	ReversalStatus string `json:"reversal_status,omitempty" db:"-"`
+}
+
+const (
+	TransferReversalNone    = "none"
+	TransferReversalPartial = "partially_reversed"
+	TransferReversalFull    = "reversed"
+)
+
+// SetReversalStatus derives ReversalStatus from ReversedAmount. Reversals themselves can not
+// be reversed and have no status.
+func (t *Transfer) SetReversalStatus() {
+	switch {
+	case t.ReversalOf != nil:
+		t.ReversalStatus = ""
+	case t.ReversedAmount == 0:
+		t.ReversalStatus = TransferReversalNone
+	case t.ReversedAmount < t.ToAmount:
+		t.ReversalStatus = TransferReversalPartial
+	default:
+		t.ReversalStatus = TransferReversalFull
+	}
+}
+
+const (
+	TransferDirectionSent     = "sent"
+	TransferDirectionReceived = "received"
+)
+
+// TransferFilter narrows down the transfers of an account. Zero values leave the
+// corresponding filter out.
+type TransferFilter struct {
+	Direction string
+	Since     *time.Time
+	Until     *time.Time
+	MinAmount int64
+	MaxAmount int64
+}
+
+// IdempotencyKey is the response recorded for a client supplied Idempotency-Key,
+// so that a retried request can be answered without moving the money again.
+type IdempotencyKey struct {
+	Key            string    `db:"key"`
+	RequestHash    string    `db:"request_hash"`
+	ResponseStatus int       `db:"response_status"`
+	ResponseBody   []byte    `db:"response_body"`
+	CreatedAt      time.Time `db:"created_at"`
+
+	// RenderBody builds the response body from the persisted transfer, it is called
+	// inside the transfer transaction so the recorded body matches what was committed.
+	RenderBody func(*Transfer) ([]byte, error) `db:"-"`
+}
+
+func ValidateTransfer(v *validator.Validator, t *Transfer) {
+	v.Check(t.FromAccountID > 0, "from_account_id", "must be provided")
+	v.Check(t.ToAccountID > 0, "to_account_id", "must be provided")
+	v.Check(t.FromAccountID != t.ToAccountID, "to_account_id", "must be different from from_account_id")
+	v.Check(t.Amount > 0, "amount", "must be greater than zero")
 }
 
 type TransferStore interface {
-	CreateTransfer(t *Transfer) error
+	CreateTransfer(ctx context.Context, t *Transfer, key *IdempotencyKey) error
+	Reverse(ctx context.Context, id int64, amount int64, key *IdempotencyKey) (*Transfer, error)
+	GetIdempotencyKey(ctx context.Context, key string) (*IdempotencyKey, error)
+	Get(ctx context.Context, id int64) (*Transfer, error)
+	ListForAccount(ctx context.Context, accountID int64, filter TransferFilter) ([]*Transfer, error)
 }
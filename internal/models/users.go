@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/validator"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type User struct {
+	ID           int64     `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Email        string    `json:"email" db:"email"`
+	PasswordHash []byte    `json:"-" db:"password_hash"`
+	Role         string    `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// AnonymousUser is set on requests that carry no authentication token.
+var AnonymousUser = &User{}
+
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
+// SetPassword stores the bcrypt hash of the plaintext password.
+func (u *User) SetPassword(plaintext string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plaintext), 12)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return nil
+}
+
+// PasswordMatches reports whether plaintext is the user's password. Users without a usable
+// password hash never match.
+func (u *User) PasswordMatches(plaintext string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(plaintext))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword), errors.Is(err, bcrypt.ErrHashTooShort):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func ValidateEmail(v *validator.Validator, email string) {
+	v.Check(email != "", "email", "must be provided")
+	v.Check(validator.Matches(email, validator.EmailRX), "email", "must be a valid email address")
+}
+
+// ValidatePasswordPlaintext checks the password length, bcrypt ignores anything past 72 bytes.
+func ValidatePasswordPlaintext(v *validator.Validator, password string) {
+	v.Check(password != "", "password", "must be provided")
+	v.Check(len(password) >= 8, "password", "must be at least 8 bytes long")
+	v.Check(len(password) <= 72, "password", "must not be more than 72 bytes long")
+}
+
+func ValidateUser(v *validator.Validator, u *User, password string) {
+	v.Check(strings.TrimSpace(u.Name) != "", "name", "must be provided")
+	v.Check(len(u.Name) <= 500, "name", "must not be more than 500 bytes long")
+	ValidateEmail(v, u.Email)
+	ValidatePasswordPlaintext(v, password)
+}
+
+type UserStore interface {
+	Get(ctx context.Context, id int64) (*User, error)
+	Insert(ctx context.Context, u *User) error
+	UpdateRole(ctx context.Context, u *User) error
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetForToken(ctx context.Context, scope, plaintext string) (*User, error)
+}
@@ -0,0 +1,86 @@
+// Package ratelimit limits how often a client may call the API. Clients are identified by an
+// opaque key and each key gets a token bucket of its own.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limiter decides whether a request of the client identified by key may proceed. When it may
+// not, retryAfter is how long the client has to wait for the next one to be allowed.
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	Allow(key string) (ok bool, retryAfter time.Duration)
+}
+
+// Memory is a Limiter keeping its buckets in the process, so every instance of the API
+// enforces the limit on its own.
+type Memory struct {
+	limit   rate.Limit
+	burst   int
+	mu      sync.Mutex
+	clients map[string]*client
+}
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemory returns a Memory limiter refilling rps tokens per second into buckets holding up
+// to burst tokens.
+func NewMemory(rps float64, burst int) *Memory {
+	return &Memory{
+		limit:   rate.Limit(rps),
+		burst:   burst,
+		clients: make(map[string]*client),
+	}
+}
+
+func (m *Memory) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	m.mu.Lock()
+	c, ok := m.clients[key]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(m.limit, m.burst)}
+		m.clients[key] = c
+	}
+	c.lastSeen = now
+	m.mu.Unlock()
+
+	r := c.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		// the token is only taken when the request goes ahead
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Sweep forgets the clients that have not been seen for idle, their buckets have refilled by
+// then anyway when idle is at least burst/rps.
+func (m *Memory) Sweep(idle time.Duration) {
+	cutoff := time.Now().Add(-idle)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, c := range m.clients {
+		if c.lastSeen.Before(cutoff) {
+			delete(m.clients, key)
+		}
+	}
+}
+
+// Len returns the number of clients being tracked.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.clients)
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemory_Allow(t *testing.T) {
+	m := NewMemory(1, 3)
+	for i := 0; i < 3; i++ {
+		if ok, _ := m.Allow("user:1"); !ok {
+			t.Fatalf("expected request %d to be allowed within the burst", i+1)
+		}
+	}
+	ok, retryAfter := m.Allow("user:1")
+	if ok {
+		t.Fatal("expected the request past the burst to be denied")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("expected to retry within a second, but got %s", retryAfter)
+	}
+	if ok, _ := m.Allow("user:2"); !ok {
+		t.Error("expected another client to have a bucket of its own")
+	}
+
+	// a denied request does not take a token, so waiting retryAfter is enough
+	time.Sleep(retryAfter)
+	if ok, _ := m.Allow("user:1"); !ok {
+		t.Error("expected the request to be allowed after waiting retryAfter")
+	}
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	m := NewMemory(1, 1)
+	m.Allow("ip:192.0.2.1")
+	m.Sweep(time.Hour)
+	if m.Len() != 1 {
+		t.Fatalf("expected a recent client to be kept, but %d are tracked", m.Len())
+	}
+	m.Sweep(0)
+	if m.Len() != 0 {
+		t.Errorf("expected the idle client to be forgotten, but %d are tracked", m.Len())
+	}
+}
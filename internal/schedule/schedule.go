@@ -0,0 +1,106 @@
+// Package schedule parses the recurrence rules of scheduled transfers.
+//
+// A rule is one of
+//
+//	""              runs once, at the start time
+//	"@every <dur>"  runs every Go duration, at least a minute, e.g. "@every 36h"
+//	"@daily"        runs every day at the time of day of the start time
+//	"@weekly"       runs every week on the weekday of the start time
+//	"@monthly"      runs every month on the day of the month of the start time, or on the
+//	                last day of shorter months
+//
+// All occurrences are computed from the start time, so a monthly rule started on the 31st
+// comes back to the 31st after running on the 30th of April.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// MinInterval is the shortest interval accepted by "@every".
+const MinInterval = time.Minute
+
+type Rule struct {
+	spec   string
+	every  time.Duration
+	months int
+}
+
+func Parse(spec string) (Rule, error) {
+	r := Rule{spec: spec}
+	switch {
+	case spec == "":
+	case spec == "@daily":
+		r.every = 24 * time.Hour
+	case spec == "@weekly":
+		r.every = 7 * 24 * time.Hour
+	case spec == "@monthly":
+		r.months = 1
+	case strings.HasPrefix(spec, "@every "):
+		d, err := time.ParseDuration(strings.TrimPrefix(spec, "@every "))
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %s", ErrInvalidRule, err)
+		}
+		if d < MinInterval {
+			return Rule{}, fmt.Errorf("%w: the interval must be at least %s", ErrInvalidRule, MinInterval)
+		}
+		r.every = d
+	default:
+		return Rule{}, fmt.Errorf("%w: %q", ErrInvalidRule, spec)
+	}
+	return r, nil
+}
+
+func (r Rule) String() string {
+	return r.spec
+}
+
+// Recurring reports whether the rule runs more than once.
+func (r Rule) Recurring() bool {
+	return r.every > 0 || r.months > 0
+}
+
+// Next returns the first occurrence of the rule started at start that lies strictly after
+// after. It returns false once a one-off rule has run.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	if after.Before(start) {
+		return start, true
+	}
+	if !r.Recurring() {
+		return time.Time{}, false
+	}
+
+	if r.every > 0 {
+		n := after.Sub(start)/r.every + 1
+		return start.Add(n * r.every), true
+	}
+
+	// estimate the number of months elapsed, then step forward past after
+	n := (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+	if n < 1 {
+		n = 1
+	}
+	for {
+		next := addMonths(start, n*r.months)
+		if next.After(after) {
+			return next, true
+		}
+		n++
+	}
+}
+
+// addMonths adds n months to t, clamping the day to the length of the resulting month.
+func addMonths(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
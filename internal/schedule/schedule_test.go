@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{"", true},
+		{"@daily", true},
+		{"@weekly", true},
+		{"@monthly", true},
+		{"@every 36h", true},
+		{"@every 30s", false},
+		{"@every soon", false},
+		{"0 9 1 * *", false},
+		{"@hourly", false},
+	}
+	for _, e := range tests {
+		_, err := Parse(e.spec)
+		if e.valid && err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", e.spec, err)
+		}
+		if !e.valid && !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Parse(%q): expected ErrInvalidRule, but got %v", e.spec, err)
+		}
+	}
+}
+
+func TestRule_Next(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name  string
+		spec  string
+		start string
+		after string
+		next  string
+		ok    bool
+	}{
+		{"one-off before start", "", "2024-03-01T09:00:00Z", "2024-02-01T00:00:00Z", "2024-03-01T09:00:00Z", true},
+		{"one-off done", "", "2024-03-01T09:00:00Z", "2024-03-01T09:00:00Z", "", false},
+		{"daily", "@daily", "2024-03-01T09:00:00Z", "2024-03-01T09:00:00Z", "2024-03-02T09:00:00Z", true},
+		{"daily catches up", "@daily", "2024-03-01T09:00:00Z", "2024-03-10T12:00:00Z", "2024-03-11T09:00:00Z", true},
+		{"weekly", "@weekly", "2024-03-01T09:00:00Z", "2024-03-05T00:00:00Z", "2024-03-08T09:00:00Z", true},
+		{"every", "@every 90m", "2024-03-01T09:00:00Z", "2024-03-01T10:00:00Z", "2024-03-01T10:30:00Z", true},
+		{"monthly", "@monthly", "2024-01-01T09:00:00Z", "2024-01-01T09:00:00Z", "2024-02-01T09:00:00Z", true},
+		{"monthly short month", "@monthly", "2024-01-31T09:00:00Z", "2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z", true},
+		{"monthly back to the 31st", "@monthly", "2024-01-31T09:00:00Z", "2024-02-29T09:00:00Z", "2024-03-31T09:00:00Z", true},
+		{"monthly across years", "@monthly", "2024-12-15T09:00:00Z", "2024-12-20T00:00:00Z", "2025-01-15T09:00:00Z", true},
+	}
+	for _, e := range tests {
+		t.Run(e.name, func(t *testing.T) {
+			rule, err := Parse(e.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next, ok := rule.Next(date(e.start), date(e.after))
+			if ok != e.ok {
+				t.Fatalf("expected ok %t, but got %t", e.ok, ok)
+			}
+			if ok && !next.Equal(date(e.next)) {
+				t.Errorf("expected next run at %s, but got %s", e.next, next.Format(time.RFC3339))
+			}
+		})
+	}
+}
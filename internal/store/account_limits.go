@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrLimitExceeded = errors.New("transfer limit exceeded")
+
+type AccountLimitStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+// Get returns the limits of the account, an account without limits gets a record without any.
+func (store AccountLimitStore) Get(ctx context.Context, accountID int64) (*models.AccountLimits, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	return getAccountLimits(ctx, store.db, accountID)
+}
+
+// Put replaces the limits of the account.
+func (store AccountLimitStore) Put(ctx context.Context, l *models.AccountLimits) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `
+		INSERT INTO account_limits (account_id, max_transfer_amount, max_daily_amount, max_daily_count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (account_id) DO UPDATE SET
+			max_transfer_amount = EXCLUDED.max_transfer_amount,
+			max_daily_amount = EXCLUDED.max_daily_amount,
+			max_daily_count = EXCLUDED.max_daily_count,
+			updated_at = now()
+		RETURNING *`
+	args := []any{l.AccountID, l.MaxTransferAmount, l.MaxDailyAmount, l.MaxDailyCount}
+	return store.db.QueryRowxContext(ctx, query, args...).StructScan(l)
+}
+
+func getAccountLimits(ctx context.Context, q sqlx.QueryerContext, accountID int64) (*models.AccountLimits, error) {
+	var l models.AccountLimits
+	err := sqlx.GetContext(ctx, q, &l, "SELECT * FROM account_limits WHERE account_id = $1", accountID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return &models.AccountLimits{AccountID: accountID}, nil
+		default:
+			return nil, err
+		}
+	}
+	return &l, nil
+}
+
+// checkLimits returns ErrLimitExceeded when sending amount from the account breaks one of its
+// limits. It must run in the transfer transaction after the payer row has been locked, so that
+// concurrent transfers from the account are counted one after the other. Reversals do not count
+// towards the daily limits.
+func checkLimits(ctx context.Context, tx *sqlx.Tx, accountID, amount int64) error {
+	l, err := getAccountLimits(ctx, tx, accountID)
+	if err != nil {
+		return err
+	}
+	if l.MaxTransferAmount != nil && amount > *l.MaxTransferAmount {
+		return fmt.Errorf("%w: the amount is above the maximum of %d per transfer", ErrLimitExceeded, *l.MaxTransferAmount)
+	}
+	if l.MaxDailyAmount == nil && l.MaxDailyCount == nil {
+		return nil
+	}
+
+	var sent struct {
+		Amount int64 `db:"amount"`
+		Count  int   `db:"count"`
+	}
+	err = tx.GetContext(
+		ctx,
+		&sent,
+		`SELECT COALESCE(SUM(amount), 0)::bigint AS amount, COUNT(*) AS count FROM transfers
+		WHERE from_account_id = $1 AND reversal_of IS NULL
+		AND created_at >= date_trunc('day', now() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'`,
+		accountID,
+	)
+	if err != nil {
+		return err
+	}
+	if l.MaxDailyAmount != nil && sent.Amount+amount > *l.MaxDailyAmount {
+		return fmt.Errorf("%w: %d of the daily maximum of %d has been sent today", ErrLimitExceeded, sent.Amount, *l.MaxDailyAmount)
+	}
+	if l.MaxDailyCount != nil && sent.Count+1 > *l.MaxDailyCount {
+		return fmt.Errorf("%w: the daily maximum of %d transfers has been reached", ErrLimitExceeded, *l.MaxDailyCount)
+	}
+	return nil
+}
@@ -1,20 +1,50 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/jmoiron/sqlx"
 )
 
 type AccountStore struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	timeouts Timeouts
 }
 
-func (store AccountStore) Get(id int64) (*models.Account, error) {
+var (
+	ErrAccountFrozen           = errors.New("account is frozen")
+	ErrAccountClosed           = errors.New("account is closed")
+	ErrInvalidStatusTransition = errors.New("account status does not allow this change")
+	ErrNonZeroBalance          = errors.New("account balance must be zero to close it")
+)
+
+// updateBalanceQuery adds $1 to the balance of account $2, keeping overdrawn_since in step with
+// the sign of the new balance. The length of an overdrawn stretch that ends is added to
+// overdrawn_seconds.
+const updateBalanceQuery = `
+	UPDATE accounts SET balance = balance + $1,
+		overdrawn_since = CASE WHEN balance + $1 < 0 THEN COALESCE(overdrawn_since, now()) END,
+		overdrawn_seconds = overdrawn_seconds + CASE
+			WHEN balance + $1 >= 0 AND overdrawn_since IS NOT NULL
+			THEN EXTRACT(EPOCH FROM now() - overdrawn_since)::bigint
+			ELSE 0
+		END
+	WHERE id=$2`
+
+func (store AccountStore) Get(ctx context.Context, id int64) (*models.Account, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
 	var acc models.Account
-	err := store.db.Get(&acc, "SELECT * FROM accounts WHERE id = $1", id)
+	err := store.db.GetContext(ctx, &acc, "SELECT * FROM accounts WHERE id = $1", id)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -26,19 +56,44 @@ func (store AccountStore) Get(id int64) (*models.Account, error) {
 	return &acc, nil
 }
 
-func (store AccountStore) Create(acc *models.Account) error {
-	query := `INSERT INTO accounts (owner, balance, currency) VALUES ($1, $2, $3) RETURNING *`
-	err := store.db.QueryRowx(query, acc.Owner, acc.Balance, acc.Currency).StructScan(acc)
+// Create opens the account, an opening balance is booked as an entry so that the ledger
+// accounts for it.
+func (store AccountStore) Create(ctx context.Context, acc *models.Account) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	tx, err := store.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	query := `INSERT INTO accounts (user_id, owner, balance, currency) VALUES ($1, $2, $3, $4) RETURNING *`
+	err = tx.QueryRowxContext(ctx, query, acc.UserID, acc.Owner, acc.Balance, acc.Currency).StructScan(acc)
+	if err != nil {
+		return err
+	}
+	if acc.Balance != 0 {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO entries (account_id, amount, reason) VALUES ($1, $2, $3)`,
+			acc.ID, acc.Balance, models.EntryReasonOpening,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-func (store AccountStore) UpdateAccount(acc *models.Account) error {
-	query := `UPDATE accounts SET owner=$1, balance=$2, currency=$3 WHERE id=$4 RETURNING *`
-	args := []any{acc.Owner, acc.Balance, acc.Currency, acc.ID}
-	err := store.db.QueryRowx(query, args...).StructScan(acc)
+// UpdateAccount updates the account details, the balance only ever moves through entries.
+func (store AccountStore) UpdateAccount(ctx context.Context, acc *models.Account) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `UPDATE accounts SET owner=$1, currency=$2 WHERE id=$3 RETURNING *`
+	args := []any{acc.Owner, acc.Currency, acc.ID}
+	err := store.db.QueryRowxContext(ctx, query, args...).StructScan(acc)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -50,44 +105,211 @@ func (store AccountStore) UpdateAccount(acc *models.Account) error {
 	return nil
 }
 
-func (store AccountStore) UpdateBalance(acc *models.Account) error {
-	query := `UPDATE accounts SET balance=$1 WHERE id=$2 RETURNING *`
-	err := store.db.QueryRowx(query, acc.Balance, acc.ID).StructScan(acc)
+// Adjust books the entry on its account and moves the balance by the entry amount in a
+// single transaction. A debit that would take the balance below the overdraft limit, or
+// spend funds that are held, is rejected, as are debits on a frozen account and any entry
+// on a closed account.
+func (store AccountStore) Adjust(ctx context.Context, e *models.Entry) (*models.Account, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	tx, err := store.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var acc models.Account
+	err = tx.GetContext(ctx, &acc, "SELECT * FROM accounts WHERE id=$1 FOR NO KEY UPDATE", e.AccountID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if acc.Status == models.AccountStatusClosed {
+		return nil, ErrAccountClosed
+	}
+	if e.Amount < 0 && acc.Status == models.AccountStatusFrozen {
+		return nil, ErrAccountFrozen
+	}
+	if e.Amount < 0 {
+		held, err := heldAmount(ctx, tx, acc.ID)
+		if err != nil {
+			return nil, err
+		}
+		if acc.Available()-held+e.Amount < 0 {
+			return nil, ErrInsufficientBalance
+		}
 	}
-	return nil
-}
 
-func (store AccountStore) List() ([]*models.Account, error) {
-	var accounts []*models.Account
-	query := `SELECT * FROM accounts ORDER BY id ASC`
-	err := store.db.Select(&accounts, query)
+	query := `INSERT INTO entries (account_id, amount, reason, actor_id, note) VALUES ($1, $2, $3, $4, $5) RETURNING *`
+	err = tx.QueryRowxContext(ctx, query, e.AccountID, e.Amount, e.Reason, e.ActorID, e.Note).StructScan(e)
 	if err != nil {
 		return nil, err
 	}
-	return accounts, nil
+	err = tx.QueryRowxContext(ctx, updateBalanceQuery+" RETURNING *", e.Amount, e.AccountID).StructScan(&acc)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &acc, nil
 }
 
-func (store AccountStore) Delete(id int64) error {
-	query := `DELETE FROM accounts WHERE id=$1`
-	result, err := store.db.Exec(query, id)
+// List returns a page of accounts using keyset pagination, the page starts right after the
+// account encoded in filter.After.
+func (store AccountStore) List(ctx context.Context, filter models.AccountFilter) ([]*models.Account, models.CursorMetadata, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	column, direction, op := "id", "ASC", ">"
+	if filter.Sort != "" {
+		column = strings.TrimPrefix(filter.Sort, "-")
+	}
+	if strings.HasPrefix(filter.Sort, "-") {
+		direction, op = "DESC", "<"
+	}
+
+	var after *accountCursor
+	if filter.After != "" {
+		var err error
+		after, err = decodeAccountCursor(filter.After, filter.Sort)
+		if err != nil {
+			return nil, models.CursorMetadata{}, err
+		}
+	}
+
+	metadata := models.CursorMetadata{Limit: filter.Limit}
+	err := store.db.GetContext(ctx, &metadata.TotalRecords, `
+		SELECT count(*) FROM accounts
+		WHERE ($1 = '' OR owner = $1) AND ($2 = '' OR currency = $2) AND ($3::bigint = 0 OR user_id = $3)
+		AND ($4::boolean OR status <> 'closed')`,
+		filter.Owner, filter.Currency, filter.UserID, filter.IncludeClosed,
+	)
 	if err != nil {
-		return err
+		return nil, models.CursorMetadata{}, err
+	}
+
+	// column and direction come from the sort safelist, never from raw user input
+	query := fmt.Sprintf(`
+		SELECT * FROM accounts
+		WHERE ($1 = '' OR owner = $1) AND ($2 = '' OR currency = $2) AND ($3::bigint = 0 OR user_id = $3)
+		AND ($4::boolean IS FALSE OR (%[1]s, id) %[2]s ($5, $6))
+		AND ($8::boolean OR status <> 'closed')
+		ORDER BY %[1]s %[3]s, id %[3]s
+		LIMIT $7`, column, op, direction)
+	args := []any{filter.Owner, filter.Currency, filter.UserID, after != nil, nil, nil, filter.Limit + 1, filter.IncludeClosed}
+	if after != nil {
+		args[4], args[5] = after.value, after.id
 	}
-	nRows, err := result.RowsAffected()
+	accounts := []*models.Account{}
+	err = store.db.SelectContext(ctx, &accounts, query, args...)
 	if err != nil {
-		return err
+		return nil, models.CursorMetadata{}, err
 	}
-	if nRows == 0 {
-		return ErrRecordNotFound
+
+	// the extra row only tells us whether there is a next page
+	if len(accounts) > filter.Limit {
+		accounts = accounts[:filter.Limit]
+		metadata.NextCursor = encodeAccountCursor(accounts[len(accounts)-1], filter.Sort)
 	}
-	return nil
+	return accounts, metadata, nil
+}
+
+func (store AccountStore) Freeze(ctx context.Context, id int64) (*models.Account, error) {
+	return store.setStatus(ctx, id, models.AccountStatusFrozen, models.AccountStatusActive)
+}
+
+func (store AccountStore) Unfreeze(ctx context.Context, id int64) (*models.Account, error) {
+	return store.setStatus(ctx, id, models.AccountStatusActive, models.AccountStatusFrozen)
 }
 
-func (store AccountStore) GetForUpdate(id int64) (*models.Account, error) {
+// Close soft deletes the account, its entries and transfers are kept. Only an account with a
+// zero balance can be closed.
+func (store AccountStore) Close(ctx context.Context, id int64) (*models.Account, error) {
+	return store.setStatus(ctx, id, models.AccountStatusClosed, models.AccountStatusActive, models.AccountStatusFrozen)
+}
+
+// SetOverdraftLimit changes how far below zero the balance of the account may go. A limit
+// below the current debt is accepted, the account can then only be paid into until the
+// debt is back within the limit.
+func (store AccountStore) SetOverdraftLimit(ctx context.Context, id int64, limit int64) (*models.Account, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	var acc models.Account
+	err := store.db.GetContext(
+		ctx,
+		&acc,
+		`UPDATE accounts SET overdraft_limit = $1 WHERE id = $2 AND status <> 'closed' RETURNING *`,
+		limit, id,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &acc, nil
+}
+
+// setStatus moves the account to status, provided its current status is one of from.
+func (store AccountStore) setStatus(ctx context.Context, id int64, status string, from ...string) (*models.Account, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	tx, err := store.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var acc models.Account
+	err = tx.GetContext(ctx, &acc, "SELECT * FROM accounts WHERE id=$1 FOR NO KEY UPDATE", id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	allowed := false
+	for _, f := range from {
+		allowed = allowed || acc.Status == f
+	}
+	if !allowed {
+		return nil, ErrInvalidStatusTransition
+	}
+	if status == models.AccountStatusClosed && acc.Balance != 0 {
+		return nil, ErrNonZeroBalance
+	}
+
+	query := `
+		UPDATE accounts SET status=$1, closed_at = CASE WHEN $1::varchar = 'closed' THEN now() END
+		WHERE id=$2 RETURNING *`
+	err = tx.QueryRowxContext(ctx, query, status, id).StructScan(&acc)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+func (store AccountStore) GetForUpdate(ctx context.Context, id int64) (*models.Account, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
 	var acc models.Account
-	err := store.db.Get(&acc, "SELECT * FROM accounts WHERE id = $1 FOR NO KEY UPDATE", id)
+	err := store.db.GetContext(ctx, &acc, "SELECT * FROM accounts WHERE id = $1 FOR NO KEY UPDATE", id)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -98,3 +320,54 @@ func (store AccountStore) GetForUpdate(id int64) (*models.Account, error) {
 	}
 	return &acc, nil
 }
+
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+type accountCursor struct {
+	value any
+	id    int64
+}
+
+// encodeAccountCursor encodes the sort key of acc along with the sort it belongs to,
+// so that a cursor can not be replayed against a different ordering.
+func encodeAccountCursor(acc *models.Account, sort string) string {
+	var value string
+	switch strings.TrimPrefix(sort, "-") {
+	case "balance":
+		value = strconv.FormatInt(acc.Balance, 10)
+	case "created_at":
+		value = acc.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		value = strconv.FormatInt(acc.ID, 10)
+	}
+	raw := fmt.Sprintf("%s|%s|%d", sort, value, acc.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAccountCursor(cursor string, sort string) (*accountCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, ErrInvalidCursor
+	}
+	var c accountCursor
+	c.id, err = strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	switch strings.TrimPrefix(sort, "-") {
+	case "balance":
+		c.value, err = strconv.ParseInt(parts[1], 10, 64)
+	case "created_at":
+		c.value, err = time.Parse(time.RFC3339Nano, parts[1])
+	default:
+		c.value, err = strconv.ParseInt(parts[1], 10, 64)
+	}
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
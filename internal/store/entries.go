@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 
@@ -9,28 +10,92 @@ import (
 )
 
 type EntryStore struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	timeouts Timeouts
 }
 
-func (store EntryStore) Create(e *models.Entry) error {
-	query := "INSERT INTO entries (account_id, amount) VALUES ($1, $2) RETURNING *"
-	err := store.db.QueryRowx(query, e.Amount, e.AccountID).StructScan(e)
+func (store EntryStore) Create(ctx context.Context, e *models.Entry) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := "INSERT INTO entries (account_id, amount, reason, actor_id, note) VALUES ($1, $2, $3, $4, $5) RETURNING *"
+	err := store.db.QueryRowxContext(ctx, query, e.AccountID, e.Amount, e.Reason, e.ActorID, e.Note).StructScan(e)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (store EntryStore) Get(accountID int64) (*models.Entry, error) {
-	var entry models.Entry
-	err := store.db.Get(&entry, "SELECT * FROM entries WHERE account_id=$1", accountID)
+// Statement returns a page of the entries booked on the account within the filter's period.
+// Balances are derived backwards from the current account balance, so the closing balance of
+// an open ended statement always matches accounts.balance.
+func (store EntryStore) Statement(ctx context.Context, accountID int64, filter models.StatementFilter) (*models.Statement, models.Metadata, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Report)
+	defer cancel()
+
+	// both queries have to see the same snapshot for the balances to add up
+	tx, err := store.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+	defer tx.Rollback()
+
+	statement := &models.Statement{
+		AccountID: accountID,
+		From:      filter.Since,
+		To:        filter.Until,
+		Lines:     []*models.StatementLine{},
+	}
+	query := `
+		SELECT
+			a.balance - COALESCE(SUM(e.amount) FILTER (WHERE $2::timestamptz IS NULL OR e.created_at >= $2), 0)::bigint,
+			a.balance - COALESCE(SUM(e.amount) FILTER (WHERE $3::timestamptz IS NOT NULL AND e.created_at >= $3), 0)::bigint
+		FROM accounts a LEFT JOIN entries e ON e.account_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.id`
+	err = tx.QueryRowxContext(ctx, query, accountID, filter.Since, filter.Until).Scan(&statement.OpeningBalance, &statement.ClosingBalance)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return nil, ErrRecordNotFound
+			return nil, models.Metadata{}, ErrRecordNotFound
 		default:
-			return nil, err
+			return nil, models.Metadata{}, err
+		}
+	}
+
+	// the running balance is computed over the whole period before the page is cut out of it
+	query = `
+		SELECT count(*) OVER() AS total, lines.* FROM (
+			SELECT e.*, $4::bigint + SUM(e.amount) OVER (ORDER BY e.created_at, e.id)::bigint AS running_balance
+			FROM entries e
+			WHERE e.account_id = $1
+			AND ($2::timestamptz IS NULL OR e.created_at >= $2)
+			AND ($3::timestamptz IS NULL OR e.created_at < $3)
+		) lines
+		ORDER BY lines.created_at, lines.id
+		LIMIT $5 OFFSET $6`
+	args := []any{accountID, filter.Since, filter.Until, statement.OpeningBalance, filter.Limit(), filter.Offset()}
+	rows, err := tx.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	for rows.Next() {
+		var line struct {
+			Total int `db:"total"`
+			models.StatementLine
+		}
+		err = rows.StructScan(&line)
+		if err != nil {
+			return nil, models.Metadata{}, err
 		}
+		totalRecords = line.Total
+		statement.Lines = append(statement.Lines, &line.StatementLine)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
 	}
-	return &entry, nil
+	return statement, models.CalculateMetadata(totalRecords, filter.Pagination), nil
 }
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// SchemaVersion is the migration the store is written against, the latest one in the
+// migrations directory.
+const SchemaVersion = 14
+
+type HealthStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func (store HealthStore) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	return store.db.PingContext(ctx)
+}
+
+// MigrationStatus reads the schema_migrations table maintained by the migrate tool, a database
+// that was never migrated has no record.
+func (store HealthStore) MigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var status models.MigrationStatus
+	err := store.db.GetContext(ctx, &status, `SELECT version, dirty FROM schema_migrations LIMIT 1`)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &status, nil
+}
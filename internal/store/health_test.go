@@ -0,0 +1,30 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	entries, err := os.ReadDir(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := int64(0)
+	for _, e := range entries {
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			t.Fatalf("%s: the migration has no version", e.Name())
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	if latest != SchemaVersion {
+		t.Errorf("expected SchemaVersion to be the latest migration %d, but it is %d", latest, SchemaVersion)
+	}
+}
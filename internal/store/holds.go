@@ -0,0 +1,265 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrHoldExpired        = errors.New("hold has expired")
+	ErrCaptureExceedsHold = errors.New("capture amount is more than the amount held")
+)
+
+type HoldStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+	// transfers carries out the transfers captured holds turn into
+	transfers TransferStore
+}
+
+// Place reserves h.Amount on its account. The account must be able to send the amount within
+// its limits, the funds held by its other holds excluded, to a payee that can receive it.
+func (store HoldStore) Place(ctx context.Context, h *models.Hold) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Transfer)
+	defer cancel()
+
+	return withRetry(ctx, func() error {
+		tx, err := store.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		// the payee is checked as the transfer of a capture would check it, so that holds
+		// that could never be captured are refused up front
+		acc, payee, err := lockTransferAccounts(ctx, tx, h.AccountID, h.ToAccountID)
+		if err != nil {
+			return err
+		}
+		if err = checkTransferable(acc); err != nil {
+			return err
+		}
+		held, err := heldAmount(ctx, tx, acc.ID)
+		if err != nil {
+			return err
+		}
+		if acc.Available()-held < h.Amount {
+			return ErrInsufficientBalance
+		}
+		if err = checkLimits(ctx, tx, acc.ID, h.Amount); err != nil {
+			return err
+		}
+		if err = checkTransferable(payee); err != nil {
+			return err
+		}
+		if err = store.transfers.convert(&models.Transfer{Amount: h.Amount}, acc.Currency, payee.Currency); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO holds (account_id, to_account_id, user_id, amount, expires_at)
+			VALUES ($1, $2, $3, $4, $5) RETURNING *`
+		args := []any{h.AccountID, h.ToAccountID, h.UserID, h.Amount, h.ExpiresAt}
+		err = tx.QueryRowxContext(ctx, query, args...).StructScan(h)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func (store HoldStore) Get(ctx context.Context, id int64) (*models.Hold, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var h models.Hold
+	err := store.db.GetContext(ctx, &h, "SELECT * FROM holds WHERE id = $1", id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &h, nil
+}
+
+// ListForAccount returns the holds placed on the account, newest first.
+func (store HoldStore) ListForAccount(ctx context.Context, accountID int64) ([]*models.Hold, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	holds := []*models.Hold{}
+	err := store.db.SelectContext(ctx, &holds, "SELECT * FROM holds WHERE account_id = $1 ORDER BY id DESC", accountID)
+	if err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// Capture transfers amount of the hold to its payee and releases the rest of it, a zero amount
+// captures the whole hold. The hold stops reserving its funds in the same transaction, so the
+// transfer can spend them.
+func (store HoldStore) Capture(ctx context.Context, id int64, amount int64) (*models.Hold, *models.Transfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Transfer)
+	defer cancel()
+
+	var h models.Hold
+	var t models.Transfer
+	err := withRetry(ctx, func() error {
+		tx, err := store.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		err = tx.GetContext(ctx, &h, "SELECT * FROM holds WHERE id = $1 FOR UPDATE", id)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+		if err = checkHoldActive(&h); err != nil {
+			return err
+		}
+		captured := amount
+		if captured == 0 {
+			captured = h.Amount
+		}
+		if captured > h.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			`UPDATE holds SET status = 'captured', captured_amount = $1, updated_at = now() WHERE id = $2`,
+			captured, h.ID,
+		)
+		if err != nil {
+			return err
+		}
+		t = models.Transfer{FromAccountID: h.AccountID, ToAccountID: h.ToAccountID, Amount: captured}
+		err = store.transfers.transfer(ctx, tx, &t)
+		if err != nil {
+			return err
+		}
+		err = tx.GetContext(ctx, &h, `UPDATE holds SET transfer_id = $1 WHERE id = $2 RETURNING *`, t.ID, h.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &h, &t, nil
+}
+
+// Release gives the funds of an active hold back to its account.
+func (store HoldStore) Release(ctx context.Context, id int64) (*models.Hold, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	tx, err := store.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var h models.Hold
+	err = tx.GetContext(ctx, &h, "SELECT * FROM holds WHERE id = $1 FOR UPDATE", id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if err = checkHoldActive(&h); err != nil {
+		return nil, err
+	}
+	err = tx.GetContext(ctx, &h, `UPDATE holds SET status = 'released', updated_at = now() WHERE id = $1 RETURNING *`, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// ExpireStale marks the active holds that expired by now as expired and returns how many did.
+// Expired holds reserve nothing whether they are marked or not, marking them only keeps their
+// status truthful.
+func (store HoldStore) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	result, err := store.db.ExecContext(
+		ctx,
+		`UPDATE holds SET status = 'expired', updated_at = now() WHERE status = 'active' AND expires_at <= $1`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (store HoldStore) Balance(ctx context.Context, accountID int64) (*models.Balance, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var b models.Balance
+	err := store.db.GetContext(ctx, &b, `
+		SELECT a.id AS account_id, a.currency, a.balance AS ledger_balance, a.overdraft_limit,
+			COALESCE(SUM(h.amount) FILTER (WHERE h.status = 'active' AND h.expires_at > now()), 0)::bigint AS held
+		FROM accounts a LEFT JOIN holds h ON h.account_id = a.id
+		WHERE a.id = $1
+		GROUP BY a.id`, accountID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	b.Available = b.LedgerBalance + b.OverdraftLimit - b.Held
+	return &b, nil
+}
+
+// heldAmount returns the funds reserved on the account by its unexpired active holds.
+func heldAmount(ctx context.Context, q sqlx.QueryerContext, accountID int64) (int64, error) {
+	var held int64
+	err := sqlx.GetContext(
+		ctx,
+		q,
+		&held,
+		`SELECT COALESCE(SUM(amount), 0)::bigint FROM holds WHERE account_id = $1 AND status = 'active' AND expires_at > now()`,
+		accountID,
+	)
+	return held, err
+}
+
+// checkHoldActive reports why h can no longer be captured or released, if it can not.
+func checkHoldActive(h *models.Hold) error {
+	switch {
+	case h.Status != models.HoldStatusActive:
+		return ErrHoldNotActive
+	case !h.ExpiresAt.After(time.Now()):
+		return ErrHoldExpired
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type LedgerStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+// Reconcile reports every account whose balance differs from the sum of its entries. With fix
+// set, a correction entry for the drift is booked on each of them so that the ledger agrees with
+// the balance again, the balance itself is left untouched.
+func (store LedgerStore) Reconcile(ctx context.Context, fix bool, actorID *int64) ([]*models.Discrepancy, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Report)
+	defer cancel()
+
+	query := `
+		SELECT a.id AS account_id, a.balance,
+			COALESCE(SUM(e.amount), 0)::bigint AS ledger_balance,
+			(a.balance - COALESCE(SUM(e.amount), 0))::bigint AS drift
+		FROM accounts a LEFT JOIN entries e ON e.account_id = a.id
+		GROUP BY a.id
+		HAVING a.balance <> COALESCE(SUM(e.amount), 0)
+		ORDER BY a.id`
+	discrepancies := []*models.Discrepancy{}
+	err := store.db.SelectContext(ctx, &discrepancies, query)
+	if err != nil {
+		return nil, err
+	}
+	if !fix {
+		return discrepancies, nil
+	}
+	for _, d := range discrepancies {
+		err = store.correct(ctx, d, actorID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return discrepancies, nil
+}
+
+// correct books the drift of a single account. The account row is locked and the drift computed
+// again, since transfers may have moved the balance since the report was taken.
+func (store LedgerStore) correct(ctx context.Context, d *models.Discrepancy, actorID *int64) error {
+	tx, err := store.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.GetContext(ctx, &d.Balance, "SELECT balance FROM accounts WHERE id=$1 FOR NO KEY UPDATE", d.AccountID)
+	if err != nil {
+		return err
+	}
+	err = tx.GetContext(ctx, &d.LedgerBalance, "SELECT COALESCE(SUM(amount), 0)::bigint FROM entries WHERE account_id=$1", d.AccountID)
+	if err != nil {
+		return err
+	}
+	d.Drift = d.Balance - d.LedgerBalance
+	if d.Drift == 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO entries (account_id, amount, reason, actor_id, note) VALUES ($1, $2, $3, $4, $5)`,
+		d.AccountID, d.Drift, models.EntryReasonCorrection, actorID, "ledger reconciliation",
+	)
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	d.Corrected = true
+	return nil
+}
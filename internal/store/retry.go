@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	maxRetries     = 5
+	retryBaseDelay = 10 * time.Millisecond
+)
+
+// withRetry runs fn, a whole transaction, again when Postgres aborted it because of a
+// serialization failure or a deadlock. Each attempt waits twice as long as the previous one,
+// with jitter so that the transactions that collided do not collide again. It gives up early
+// once ctx is done.
+func withRetry(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !isRetryable(err) || attempt == maxRetries {
+			return err
+		}
+		delay := retryBaseDelay << attempt
+		timer := time.NewTimer(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether err is a serialization_failure or deadlock_detected error.
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
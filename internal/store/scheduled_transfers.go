@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type ScheduledTransferStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func (store ScheduledTransferStore) Create(ctx context.Context, st *models.ScheduledTransfer) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `
+		INSERT INTO scheduled_transfers (user_id, from_account_id, to_account_id, amount, schedule, start_at, next_run_at, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *`
+	args := []any{st.UserID, st.FromAccountID, st.ToAccountID, st.Amount, st.Schedule, st.StartAt, st.NextRunAt, st.Active}
+	return store.db.QueryRowxContext(ctx, query, args...).StructScan(st)
+}
+
+func (store ScheduledTransferStore) Get(ctx context.Context, id int64) (*models.ScheduledTransfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var st models.ScheduledTransfer
+	err := store.db.GetContext(ctx, &st, "SELECT * FROM scheduled_transfers WHERE id = $1", id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &st, nil
+}
+
+func (store ScheduledTransferStore) List(ctx context.Context, userID int64) ([]*models.ScheduledTransfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	scheduled := []*models.ScheduledTransfer{}
+	err := store.db.SelectContext(ctx, &scheduled, `
+		SELECT * FROM scheduled_transfers WHERE ($1::bigint = 0 OR user_id = $1) ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// Update changes the amount and the schedule of st, along with when and whether it runs next.
+func (store ScheduledTransferStore) Update(ctx context.Context, st *models.ScheduledTransfer) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `UPDATE scheduled_transfers SET amount=$1, schedule=$2, next_run_at=$3, active=$4 WHERE id=$5 RETURNING *`
+	err := store.db.QueryRowxContext(ctx, query, st.Amount, st.Schedule, st.NextRunAt, st.Active, st.ID).StructScan(st)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the scheduled transfer along with its runs, the transfers it executed stay.
+func (store ScheduledTransferStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	result, err := store.db.ExecContext(ctx, "DELETE FROM scheduled_transfers WHERE id=$1", id)
+	if err != nil {
+		return err
+	}
+	nRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if nRows == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+func (store ScheduledTransferStore) Due(ctx context.Context, now time.Time, limit int) ([]*models.ScheduledTransfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	due := []*models.ScheduledTransfer{}
+	err := store.db.SelectContext(ctx, &due, `
+		SELECT * FROM scheduled_transfers
+		WHERE active AND next_run_at <= $1
+		ORDER BY next_run_at, id
+		LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// RecordRun stores the run unless it has been recorded before, and advances the scheduled
+// transfer unless another scheduler already did. The run may be due after the scheduled
+// transfer's next run when the runs in between were skipped.
+func (store ScheduledTransferStore) RecordRun(ctx context.Context, run *models.ScheduledTransferRun, next *time.Time) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	tx, err := store.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO scheduled_transfer_runs (scheduled_transfer_id, due_at, status, transfer_id, error)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scheduled_transfer_id, due_at) DO NOTHING
+		RETURNING *`
+	args := []any{run.ScheduledTransferID, run.DueAt, run.Status, run.TransferID, run.Error}
+	err = tx.QueryRowxContext(ctx, query, args...).StructScan(run)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE scheduled_transfers SET next_run_at = $1, active = active AND $1::timestamptz IS NOT NULL
+		WHERE id = $2 AND next_run_at <= $3`,
+		next, run.ScheduledTransferID, run.DueAt,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListRuns returns the runs of the scheduled transfer, latest first.
+func (store ScheduledTransferStore) ListRuns(ctx context.Context, id int64) ([]*models.ScheduledTransferRun, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	runs := []*models.ScheduledTransferRun{}
+	err := store.db.SelectContext(ctx, &runs, `
+		SELECT * FROM scheduled_transfer_runs WHERE scheduled_transfer_id = $1 ORDER BY due_at DESC`, id)
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
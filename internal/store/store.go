@@ -1,8 +1,11 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/Ruthvik10/simple_bank/internal/exchange"
 	mock "github.com/Ruthvik10/simple_bank/internal/mock/db"
 	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/jmoiron/sqlx"
@@ -13,23 +16,100 @@ var (
 )
 
 type Store struct {
-	Account  models.AccountStore
-	Transfer models.TransferStore
+	Account           models.AccountStore
+	Transfer          models.TransferStore
+	Entry             models.EntryStore
+	User              models.UserStore
+	Token             models.TokenStore
+	Ledger            models.LedgerStore
+	ScheduledTransfer models.ScheduledTransferStore
+	Health            models.HealthStore
+	AccountLimit      models.AccountLimitStore
+	Hold              models.HoldStore
 }
 
-func NewStore(db *sqlx.DB) Store {
+// Timeouts bounds how long a single store operation may run, on top of any deadline of the
+// caller's context. A zero duration leaves the operation bounded by the caller only.
+type Timeouts struct {
+	// Read covers lookups and listings.
+	Read time.Duration
+	// Write covers inserts, updates and balance adjustments.
+	Write time.Duration
+	// Transfer covers a whole transfer, retries included.
+	Transfer time.Duration
+	// Report covers statements and ledger reconciliation.
+	Report time.Duration
+}
+
+// withTimeout derives the context a single operation runs with.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// NewStore returns a Store backed by db. Cross currency transfers are converted with rates,
+// pass nil to reject them.
+func NewStore(db *sqlx.DB, rates exchange.RateProvider, timeouts Timeouts) Store {
+	transfers := TransferStore{
+		db:       db,
+		timeouts: timeouts,
+		rates:    rates,
+	}
 	return Store{
 		Account: AccountStore{
-			db: db,
+			db:       db,
+			timeouts: timeouts,
+		},
+		Transfer: transfers,
+		Entry: EntryStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		User: UserStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		Token: TokenStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		Ledger: LedgerStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		ScheduledTransfer: ScheduledTransferStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		Health: HealthStore{
+			db:       db,
+			timeouts: timeouts,
+		},
+		AccountLimit: AccountLimitStore{
+			db:       db,
+			timeouts: timeouts,
 		},
-		Transfer: TransferStore{
-			db: db,
+		Hold: HoldStore{
+			db:        db,
+			timeouts:  timeouts,
+			transfers: transfers,
 		},
 	}
 }
 
 func NewMockStore() Store {
 	return Store{
-		Account: mock.MockAccountStore{},
+		Account:           mock.MockAccountStore{},
+		Transfer:          mock.MockTransferStore{},
+		Entry:             mock.MockEntryStore{},
+		User:              mock.MockUserStore{},
+		Token:             mock.MockTokenStore{},
+		Ledger:            mock.MockLedgerStore{},
+		ScheduledTransfer: mock.MockScheduledTransferStore{},
+		Health:            mock.MockHealthStore{},
+		AccountLimit:      mock.MockAccountLimitStore{},
+		Hold:              mock.MockHoldStore{},
 	}
 }
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+type TokenStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func (store TokenStore) Insert(ctx context.Context, t *models.Token) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `INSERT INTO tokens (hash, user_id, expiry, scope) VALUES ($1, $2, $3, $4)`
+	_, err := store.db.ExecContext(ctx, query, t.Hash, t.UserID, t.Expiry, t.Scope)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (store TokenStore) DeleteAllForUser(ctx context.Context, scope string, userID int64) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `DELETE FROM tokens WHERE scope = $1 AND user_id = $2`
+	_, err := store.db.ExecContext(ctx, query, scope, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -4,63 +4,287 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"math/big"
 
+	"github.com/Ruthvik10/simple_bank/internal/exchange"
 	"github.com/Ruthvik10/simple_bank/internal/models"
 	"github.com/jmoiron/sqlx"
 )
 
 type TransferStore struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	timeouts Timeouts
+	// rates converts cross currency transfers, they are rejected when it is nil
+	rates exchange.RateProvider
 }
 
 var (
 	ErrInsufficientBalance = errors.New("insufficent balance")
 	ErrInvalidPayer        = errors.New("invalid payer details")
 	ErrInvalidPayee        = errors.New("invalid payee details")
+
+	ErrCurrencyMismatch        = errors.New("payer and payee accounts hold different currencies")
+	ErrUnsupportedCurrencyPair = errors.New("no exchange rate available for the currency pair")
+	ErrInvalidAmount           = errors.New("amount is too small to be credited in the payee's currency")
+
+	ErrDuplicateIdempotencyKey = errors.New("idempotency key has already been used")
+
+	ErrTransferIsReversal      = errors.New("a reversal can not be reversed")
+	ErrTransferReversed        = errors.New("transfer has already been fully reversed")
+	ErrReversalExceedsTransfer = errors.New("reversal amount is more than what is left to reverse")
 )
 
-func (store TransferStore) CreateTransfer(t *models.Transfer) error {
-	tx, err := store.db.BeginTxx(context.Background(), nil)
+// CreateTransfer moves t.Amount from the payer to the payee. When key is not nil it is
+// recorded in the same transaction, so the transfer and its idempotency key are either
+// both committed or both discarded. The transaction is retried when Postgres aborts it
+// with a serialization failure or a deadlock.
+func (store TransferStore) CreateTransfer(ctx context.Context, t *models.Transfer, key *models.IdempotencyKey) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Transfer)
+	defer cancel()
+
+	return withRetry(ctx, func() error {
+		return store.createTransfer(ctx, t, key)
+	})
+}
+
+func (store TransferStore) createTransfer(ctx context.Context, t *models.Transfer, key *models.IdempotencyKey) error {
+	tx, err := store.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	// read from_account info
-	var fromAccount models.Account
-	err = tx.Get(&fromAccount, "SELECT * FROM accounts WHERE id=$1 FOR NO KEY UPDATE", t.FromAccountID)
+
+	if err = claimIdempotencyKey(ctx, tx, key); err != nil {
+		return err
+	}
+
+	err = store.transfer(ctx, tx, t)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrInvalidPayer
-		default:
+		return err
+	}
+
+	if err = recordIdempotencyKey(ctx, tx, key, t); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Reverse sends amount of the transfer back from its payee to its payer in a new transfer
+// that references it, a zero amount reverses whatever has not been reversed yet. Amounts are
+// in the payee's currency and converted back at the rate of the original transfer, the last
+// reversal credits whatever the earlier ones left so that the payer gets back exactly what
+// was sent. The original transfer is locked while its reversals are added up, so concurrent
+// reversals can never send back more than it credited. key is handled as in CreateTransfer.
+func (store TransferStore) Reverse(ctx context.Context, id int64, amount int64, key *models.IdempotencyKey) (*models.Transfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Transfer)
+	defer cancel()
+
+	var reversal models.Transfer
+	err := withRetry(ctx, func() error {
+		tx, err := store.db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err = claimIdempotencyKey(ctx, tx, key); err != nil {
 			return err
 		}
+
+		var original models.Transfer
+		err = tx.GetContext(ctx, &original, "SELECT * FROM transfers WHERE id=$1 FOR NO KEY UPDATE", id)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrRecordNotFound
+			default:
+				return err
+			}
+		}
+		if original.ReversalOf != nil {
+			return ErrTransferIsReversal
+		}
+		reversed, err := getReversedAmounts(ctx, tx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := original.ToAmount - reversed.Amount
+		if remaining <= 0 {
+			return ErrTransferReversed
+		}
+		reverse := amount
+		if reverse == 0 {
+			reverse = remaining
+		}
+		if reverse > remaining {
+			return fmt.Errorf("%w: %d of %d is left", ErrReversalExceedsTransfer, remaining, original.ToAmount)
+		}
+
+		// convert back at the original rate, rounding never credits more than is left
+		rate := big.NewRat(original.Amount, original.ToAmount)
+		refundable := original.Amount - reversed.ToAmount
+		refund := refundable
+		if reverse < remaining {
+			refund, err = exchange.Convert(reverse, rate)
+			if err != nil {
+				return err
+			}
+			if refund > refundable {
+				refund = refundable
+			}
+		}
+		if refund <= 0 {
+			return ErrInvalidAmount
+		}
+
+		reversal = models.Transfer{
+			FromAccountID: original.ToAccountID,
+			ToAccountID:   original.FromAccountID,
+			Amount:        reverse,
+			Currency:      original.ToCurrency,
+			ToAmount:      refund,
+			ToCurrency:    original.Currency,
+			ExchangeRate:  exchange.FormatRate(rate),
+			ReversalOf:    &original.ID,
+		}
+		err = store.transfer(ctx, tx, &reversal)
+		if err != nil {
+			return err
+		}
+
+		if err = recordIdempotencyKey(ctx, tx, key, &reversal); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, err
 	}
-	if fromAccount.Balance < t.Amount {
-		return ErrInsufficientBalance
+	return &reversal, nil
+}
+
+// claimIdempotencyKey inserts key, when it is not nil, before anything else happens in tx. A
+// concurrent request with the same key blocks here until tx finishes and then finds the key
+// taken.
+func claimIdempotencyKey(ctx context.Context, tx *sqlx.Tx, key *models.IdempotencyKey) error {
+	if key == nil {
+		return nil
 	}
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO idempotency_keys (key, request_hash, response_status, response_body) VALUES ($1, $2, $3, '') ON CONFLICT (key) DO NOTHING`,
+		key.Key, key.RequestHash, key.ResponseStatus,
+	)
+	if err != nil {
+		return err
+	}
+	nRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if nRows == 0 {
+		return ErrDuplicateIdempotencyKey
+	}
+	return nil
+}
 
-	// check if the to_account exists
-	var toAccount models.Account
-	err = tx.Get(&toAccount, "SELECT * FROM accounts WHERE id=$1 FOR NO KEY UPDATE", t.ToAccountID)
+// recordIdempotencyKey records the response for key now that the transfer row exists.
+func recordIdempotencyKey(ctx context.Context, tx *sqlx.Tx, key *models.IdempotencyKey, t *models.Transfer) error {
+	if key == nil {
+		return nil
+	}
+	var err error
+	key.ResponseBody, err = key.RenderBody(t)
 	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrInvalidPayee
-		default:
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE idempotency_keys SET response_body=$1 WHERE key=$2", key.ResponseBody, key.Key)
+	return err
+}
+
+// reversedAmounts adds up the reversals of a transfer. Amount is what they took back in the
+// currency the transfer was credited in, ToAmount what they gave back to its payer.
+type reversedAmounts struct {
+	Amount   int64 `db:"amount"`
+	ToAmount int64 `db:"to_amount"`
+}
+
+func getReversedAmounts(ctx context.Context, q sqlx.QueryerContext, transferID int64) (reversedAmounts, error) {
+	var reversed reversedAmounts
+	err := sqlx.GetContext(
+		ctx,
+		q,
+		&reversed,
+		`SELECT COALESCE(SUM(amount), 0)::bigint AS amount, COALESCE(SUM(to_amount), 0)::bigint AS to_amount FROM transfers WHERE reversal_of = $1`,
+		transferID,
+	)
+	return reversed, err
+}
+
+// transfer moves t.Amount from the payer to the payee within tx. Money held on the payer's
+// account can not be transferred. Reversals are not subject to the payer's limits.
+func (store TransferStore) transfer(ctx context.Context, tx *sqlx.Tx, t *models.Transfer) error {
+	// lock both accounts in id order, so that transfers running in opposite directions
+	// between the same accounts queue up instead of deadlocking
+	fromAccount, toAccount, err := lockTransferAccounts(ctx, tx, t.FromAccountID, t.ToAccountID)
+	if err != nil {
+		return err
+	}
+	if err = checkTransferable(fromAccount); err != nil {
+		return err
+	}
+	held, err := heldAmount(ctx, tx, fromAccount.ID)
+	if err != nil {
+		return err
+	}
+	if fromAccount.Available()-held < t.Amount {
+		return ErrInsufficientBalance
+	}
+	if t.ReversalOf == nil {
+		if err = checkLimits(ctx, tx, fromAccount.ID, t.Amount); err != nil {
 			return err
 		}
 	}
+	if err = checkTransferable(toAccount); err != nil {
+		return err
+	}
+
+	// reversals come converted at the rate of the transfer they reverse, which only holds as
+	// long as the accounts still hold the currencies it moved
+	if t.ReversalOf == nil {
+		err = store.convert(t, fromAccount.Currency, toAccount.Currency)
+	} else if fromAccount.Currency != t.Currency || toAccount.Currency != t.ToCurrency {
+		err = ErrCurrencyMismatch
+	}
+	if err != nil {
+		return err
+	}
 
 	// create a transfer record
-	_, err = tx.Exec("INSERT INTO transfers (from_account_id, to_account_id, amount) VALUES ($1, $2, $3)", t.FromAccountID, t.ToAccountID, t.Amount)
+	err = tx.QueryRowxContext(
+		ctx,
+		`INSERT INTO transfers (from_account_id, to_account_id, amount, currency, to_amount, to_currency, exchange_rate, reversal_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *`,
+		t.FromAccountID, t.ToAccountID, t.Amount, t.Currency, t.ToAmount, t.ToCurrency, t.ExchangeRate, t.ReversalOf,
+	).StructScan(t)
 	if err != nil {
-		return nil
+		return err
 	}
+	t.SetReversalStatus()
 
 	// create an entry for from_account to_account
-	stmt, err := tx.Prepare(
-		`INSERT INTO entries (account_id, amount) VALUES ($1, $2)`,
+	reason := models.EntryReasonTransfer
+	if t.ReversalOf != nil {
+		reason = models.EntryReasonReversal
+	}
+	stmt, err := tx.PrepareContext(
+		ctx,
+		`INSERT INTO entries (account_id, amount, reason) VALUES ($1, $2, $3)`,
 	)
 	if err != nil {
 		return err
@@ -68,29 +292,168 @@ func (store TransferStore) CreateTransfer(t *models.Transfer) error {
 
 	defer stmt.Close()
 
-	_, err = stmt.Exec(t.FromAccountID, -t.Amount)
+	_, err = stmt.ExecContext(ctx, t.FromAccountID, -t.Amount, reason)
 	if err != nil {
 		return err
 	}
 
-	_, err = stmt.Exec(t.ToAccountID, t.Amount)
+	_, err = stmt.ExecContext(ctx, t.ToAccountID, t.ToAmount, reason)
 	if err != nil {
 		return err
 	}
 
 	// update balance for from_account
-	_, err = tx.Exec("UPDATE accounts SET balance = balance - $1 WHERE id=$2", t.Amount, t.FromAccountID)
+	_, err = tx.ExecContext(ctx, updateBalanceQuery, -t.Amount, t.FromAccountID)
 	if err != nil {
 		return err
 	}
 
 	// update balance for to_account
-	_, err = tx.Exec("UPDATE accounts SET balance = balance + $1 WHERE id=$2", t.Amount, t.ToAccountID)
+	_, err = tx.ExecContext(ctx, updateBalanceQuery, t.ToAmount, t.ToAccountID)
 	if err != nil {
 		return err
 	}
-	if err = tx.Commit(); err != nil {
+	return nil
+}
+
+// convert fills in the currencies of t and the amount credited to the payee. Amounts that
+// round down to nothing in the payee's currency are rejected with ErrInvalidAmount.
+func (store TransferStore) convert(t *models.Transfer, from, to string) error {
+	t.Currency, t.ToCurrency = from, to
+	if from == to {
+		t.ToAmount, t.ExchangeRate = t.Amount, "1"
+		return nil
+	}
+	if store.rates == nil {
+		return ErrCurrencyMismatch
+	}
+	rate, err := store.rates.Rate(from, to)
+	if err != nil {
+		switch {
+		case errors.Is(err, exchange.ErrRateNotFound):
+			return fmt.Errorf("%w: %s to %s", ErrUnsupportedCurrencyPair, from, to)
+		default:
+			return err
+		}
+	}
+	t.ToAmount, err = exchange.Convert(t.Amount, rate)
+	if err != nil {
 		return err
 	}
+	// the payer would be debited for nothing
+	if t.ToAmount <= 0 {
+		return ErrInvalidAmount
+	}
+	t.ExchangeRate = exchange.FormatRate(rate)
+	return nil
+}
+
+func (store TransferStore) GetIdempotencyKey(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var idempotencyKey models.IdempotencyKey
+	err := store.db.GetContext(ctx, &idempotencyKey, "SELECT * FROM idempotency_keys WHERE key=$1", key)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &idempotencyKey, nil
+}
+
+// reversedAmountColumn selects the reversed_amount of the transfers aliased t.
+const reversedAmountColumn = `(SELECT COALESCE(SUM(r.amount), 0) FROM transfers r WHERE r.reversal_of = t.id)::bigint AS reversed_amount`
+
+func (store TransferStore) Get(ctx context.Context, id int64) (*models.Transfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var t models.Transfer
+	err := store.db.GetContext(ctx, &t, `SELECT *, `+reversedAmountColumn+` FROM transfers t WHERE id=$1`, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	t.SetReversalStatus()
+	return &t, nil
+}
+
+// ListForAccount returns the transfers sent or received by the account, newest first.
+func (store TransferStore) ListForAccount(ctx context.Context, accountID int64, filter models.TransferFilter) ([]*models.Transfer, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	query := `
+		SELECT *, ` + reversedAmountColumn + ` FROM transfers t
+		WHERE CASE $2
+			WHEN 'sent' THEN from_account_id = $1
+			WHEN 'received' THEN to_account_id = $1
+			ELSE from_account_id = $1 OR to_account_id = $1
+		END
+		AND ($3::timestamptz IS NULL OR created_at >= $3)
+		AND ($4::timestamptz IS NULL OR created_at < $4)
+		AND ($5::bigint = 0 OR amount >= $5)
+		AND ($6::bigint = 0 OR amount <= $6)
+		ORDER BY created_at DESC, id DESC`
+	args := []any{accountID, filter.Direction, filter.Since, filter.Until, filter.MinAmount, filter.MaxAmount}
+	transfers := []*models.Transfer{}
+	err := store.db.SelectContext(ctx, &transfers, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		t.SetReversalStatus()
+	}
+	return transfers, nil
+}
+
+// lockTransferAccounts locks the payer and payee rows, lowest account id first.
+func lockTransferAccounts(ctx context.Context, tx *sqlx.Tx, fromID, toID int64) (from, to *models.Account, err error) {
+	lock := func(id int64, errNotFound error) (*models.Account, error) {
+		var acc models.Account
+		err := tx.GetContext(ctx, &acc, "SELECT * FROM accounts WHERE id=$1 FOR NO KEY UPDATE", id)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return nil, errNotFound
+			default:
+				return nil, err
+			}
+		}
+		return &acc, nil
+	}
+	if fromID <= toID {
+		if from, err = lock(fromID, ErrInvalidPayer); err != nil {
+			return nil, nil, err
+		}
+		to, err = lock(toID, ErrInvalidPayee)
+	} else {
+		if to, err = lock(toID, ErrInvalidPayee); err != nil {
+			return nil, nil, err
+		}
+		from, err = lock(fromID, ErrInvalidPayer)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// checkTransferable reports why money can not move in or out of acc, if it can not.
+func checkTransferable(acc *models.Account) error {
+	switch acc.Status {
+	case models.AccountStatusFrozen:
+		return ErrAccountFrozen
+	case models.AccountStatusClosed:
+		return ErrAccountClosed
+	}
 	return nil
 }
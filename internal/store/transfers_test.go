@@ -0,0 +1,547 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/exchange"
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// The tests in this file run against a real, migrated Postgres database whose DSN is read
+// from SIMPLE_BANK_TEST_DSN. They are skipped when it is not set.
+
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	dsn := os.Getenv("SIMPLE_BANK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("SIMPLE_BANK_TEST_DSN is not set")
+	}
+	db, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(50)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newTestAccounts opens n accounts holding balance each, owned by a fresh user.
+func newTestAccounts(t *testing.T, db *sqlx.DB, n int, balance int64) []*models.Account {
+	t.Helper()
+	user := &models.User{
+		Name:         "concurrency test",
+		Email:        fmt.Sprintf("concurrency-%d@example.com", time.Now().UnixNano()),
+		PasswordHash: []byte("unusable"),
+	}
+	err := UserStore{db: db}.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts := make([]*models.Account, n)
+	for i := range accounts {
+		accounts[i] = &models.Account{UserID: user.ID, Owner: user.Name, Balance: balance, Currency: "USD"}
+		err = AccountStore{db: db}.Create(context.Background(), accounts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return accounts
+}
+
+// runTransfers fires the transfers returned by next from workers goroutines. Transfers that
+// fail for lack of funds are expected, any other error fails the test.
+func runTransfers(t *testing.T, db *sqlx.DB, workers, count int, next func(i int) *models.Transfer) {
+	t.Helper()
+	store := TransferStore{db: db}
+	jobs := make(chan int)
+	errs := make(chan error, count)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := store.CreateTransfer(context.Background(), next(i), nil)
+				if err != nil && !errors.Is(err, ErrInsufficientBalance) {
+					errs <- err
+				}
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// assertConserved checks that the accounts still hold total between them and that every
+// balance agrees with its ledger.
+func assertConserved(t *testing.T, db *sqlx.DB, accounts []*models.Account, total int64) {
+	t.Helper()
+	var sum int64
+	for _, acc := range accounts {
+		var balance, ledger int64
+		err := db.Get(&balance, "SELECT balance FROM accounts WHERE id=$1", acc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Get(&ledger, "SELECT COALESCE(SUM(amount), 0)::bigint FROM entries WHERE account_id=$1", acc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if balance != ledger {
+			t.Errorf("account %d: balance %d disagrees with ledger %d", acc.ID, balance, ledger)
+		}
+		if balance < 0 {
+			t.Errorf("account %d: negative balance %d", acc.ID, balance)
+		}
+		sum += balance
+	}
+	if sum != total {
+		t.Errorf("expected the accounts to hold %d in total, but they hold %d", total, sum)
+	}
+}
+
+func transferCount() int {
+	if testing.Short() {
+		return 200
+	}
+	return 2000
+}
+
+func TestTransferStore_CreateTransfer_opposite_directions(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 10_000)
+	a, b := accounts[0], accounts[1]
+
+	runTransfers(t, db, 20, transferCount(), func(i int) *models.Transfer {
+		if i%2 == 0 {
+			return &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 7}
+		}
+		return &models.Transfer{FromAccountID: b.ID, ToAccountID: a.ID, Amount: 5}
+	})
+	assertConserved(t, db, accounts, 20_000)
+}
+
+func TestTransferStore_CreateTransfer_conserves_money(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 5, 1_000)
+
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(1))
+	runTransfers(t, db, 32, transferCount(), func(i int) *models.Transfer {
+		mu.Lock()
+		defer mu.Unlock()
+		from := rnd.Intn(len(accounts))
+		to := (from + 1 + rnd.Intn(len(accounts)-1)) % len(accounts)
+		return &models.Transfer{
+			FromAccountID: accounts[from].ID,
+			ToAccountID:   accounts[to].ID,
+			Amount:        1 + rnd.Int63n(100),
+		}
+	})
+	assertConserved(t, db, accounts, 5_000)
+}
+
+func TestTransferStore_CreateTransfer_limits(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 10_000)
+	a, b := accounts[0], accounts[1]
+	maxTransfer, maxDaily, maxCount := int64(1_000), int64(2_000), 3
+	err := AccountLimitStore{db: db}.Put(context.Background(), &models.AccountLimits{
+		AccountID:         a.ID,
+		MaxTransferAmount: &maxTransfer,
+		MaxDailyAmount:    &maxDaily,
+		MaxDailyCount:     &maxCount,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := TransferStore{db: db}
+
+	err = store.CreateTransfer(context.Background(), &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 1_001}, nil)
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected a transfer above the maximum to be rejected, but got %v", err)
+	}
+
+	// concurrent transfers are counted one after the other, 600 fits three times into the
+	// daily maximum and the count allows no more either
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := store.CreateTransfer(context.Background(), &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 600}, nil)
+			switch {
+			case err == nil:
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			case !errors.Is(err, ErrLimitExceeded):
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 3 {
+		t.Errorf("expected 3 transfers within the daily limits, but %d went through", succeeded)
+	}
+
+	// the limits are the payer's, b can still send
+	err = store.CreateTransfer(context.Background(), &models.Transfer{FromAccountID: b.ID, ToAccountID: a.ID, Amount: 5_000}, nil)
+	if err != nil {
+		t.Errorf("expected the payee's transfer to be unlimited, but got %v", err)
+	}
+	assertConserved(t, db, accounts, 20_000)
+}
+
+func TestTransferStore_CreateTransfer_overdraft(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 1_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	_, err := AccountStore{db: db}.SetOverdraftLimit(ctx, a.ID, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := TransferStore{db: db}
+
+	err = store.CreateTransfer(ctx, &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 1_501}, nil)
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected a transfer beyond the overdraft to be rejected, but got %v", err)
+	}
+	err = store.CreateTransfer(ctx, &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 1_500}, nil)
+	if err != nil {
+		t.Fatalf("expected a transfer within the overdraft to go through, but got %v", err)
+	}
+	acc, err := AccountStore{db: db}.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Balance != -500 || acc.OverdrawnSince == nil {
+		t.Errorf("expected the account to be overdrawn by 500 since now, but got %d since %v", acc.Balance, acc.OverdrawnSince)
+	}
+	// pretend the account went overdrawn two hours ago
+	_, err = db.Exec("UPDATE accounts SET overdrawn_since = now() - interval '2 hours' WHERE id = $1", a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.CreateTransfer(ctx, &models.Transfer{FromAccountID: b.ID, ToAccountID: a.ID, Amount: 500}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err = AccountStore{db: db}.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Balance != 0 || acc.OverdrawnSince != nil {
+		t.Errorf("expected the account to be back at zero and no longer overdrawn, but got %d since %v", acc.Balance, acc.OverdrawnSince)
+	}
+	if acc.OverdrawnSeconds < 7_200 || acc.OverdrawnSeconds > 7_260 {
+		t.Errorf("expected the two hours overdrawn to be recorded, but got %ds", acc.OverdrawnSeconds)
+	}
+}
+
+func TestHoldStore_capture_and_release(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 1_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	transfers := TransferStore{db: db}
+	holds := HoldStore{db: db, transfers: transfers}
+	place := func(amount int64) (*models.Hold, error) {
+		h := &models.Hold{AccountID: a.ID, ToAccountID: b.ID, UserID: a.UserID, Amount: amount, ExpiresAt: time.Now().Add(time.Hour)}
+		return h, holds.Place(ctx, h)
+	}
+
+	first, err := place(600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = place(401); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected a hold beyond the available balance to be rejected, but got %v", err)
+	}
+	err = transfers.CreateTransfer(ctx, &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 401}, nil)
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected a transfer spending held funds to be rejected, but got %v", err)
+	}
+	balance, err := holds.Balance(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.LedgerBalance != 1_000 || balance.Held != 600 || balance.Available != 400 {
+		t.Errorf("expected 1000 on the ledger with 600 held and 400 available, but got %+v", balance)
+	}
+
+	if _, _, err = holds.Capture(ctx, first.ID, 601); !errors.Is(err, ErrCaptureExceedsHold) {
+		t.Fatalf("expected capturing more than the hold to be rejected, but got %v", err)
+	}
+	h, transfer, err := holds.Capture(ctx, first.ID, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Status != models.HoldStatusCaptured || h.CapturedAmount != 250 || h.TransferID == nil || *h.TransferID != transfer.ID {
+		t.Errorf("expected the hold to be captured for 250 by transfer %d, but got %+v", transfer.ID, h)
+	}
+	if _, _, err = holds.Capture(ctx, first.ID, 0); !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("expected a captured hold not to be captured again, but got %v", err)
+	}
+
+	second, err := place(750)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, err = holds.Release(ctx, second.ID); err != nil || h.Status != models.HoldStatusReleased {
+		t.Fatalf("expected the hold to be released, but got %+v, %v", h, err)
+	}
+	if _, err = holds.Release(ctx, second.ID); !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("expected a released hold not to be released again, but got %v", err)
+	}
+	balance, err = holds.Balance(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.LedgerBalance != 750 || balance.Held != 0 || balance.Available != 750 {
+		t.Errorf("expected 750 on the ledger and available, but got %+v", balance)
+	}
+	assertConserved(t, db, accounts, 2_000)
+}
+
+func TestHoldStore_Place_payee(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 3, 1_000)
+	a, b, c := accounts[0], accounts[1], accounts[2]
+	ctx := context.Background()
+	holds := HoldStore{db: db, transfers: TransferStore{db: db}}
+	place := func(to int64) error {
+		h := &models.Hold{AccountID: a.ID, ToAccountID: to, UserID: a.UserID, Amount: 100, ExpiresAt: time.Now().Add(time.Hour)}
+		return holds.Place(ctx, h)
+	}
+
+	if err := place(c.ID + 1_000_000); !errors.Is(err, ErrInvalidPayee) {
+		t.Errorf("expected a hold for an unknown payee to be rejected, but got %v", err)
+	}
+	if _, err := (AccountStore{db: db}).Freeze(ctx, b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := place(b.ID); !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("expected a hold for a frozen payee to be rejected, but got %v", err)
+	}
+	if _, err := db.Exec("UPDATE accounts SET currency = 'EUR' WHERE id = $1", c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := place(c.ID); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected a hold for a payee in another currency to be rejected, but got %v", err)
+	}
+}
+
+func TestHoldStore_Place_limits(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 10_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	maxTransfer := int64(1_000)
+	err := AccountLimitStore{db: db}.Put(ctx, &models.AccountLimits{AccountID: a.ID, MaxTransferAmount: &maxTransfer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	holds := HoldStore{db: db, transfers: TransferStore{db: db}}
+
+	h := &models.Hold{AccountID: a.ID, ToAccountID: b.ID, UserID: a.UserID, Amount: 1_001, ExpiresAt: time.Now().Add(time.Hour)}
+	if err = holds.Place(ctx, h); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected a hold above the maximum per transfer to be rejected, but got %v", err)
+	}
+	h.Amount = 1_000
+	if err = holds.Place(ctx, h); err != nil {
+		t.Errorf("expected a hold within the limits to be placed, but got %v", err)
+	}
+}
+
+func TestHoldStore_ExpireStale(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 1_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	holds := HoldStore{db: db, transfers: TransferStore{db: db}}
+	h := &models.Hold{AccountID: a.ID, ToAccountID: b.ID, UserID: a.UserID, Amount: 1_000, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := holds.Place(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := holds.ExpireStale(ctx, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expired < 1 {
+		t.Fatalf("expected the hold to expire, but %d holds did", expired)
+	}
+	if h, err = holds.Get(ctx, h.ID); err != nil || h.Status != models.HoldStatusExpired {
+		t.Fatalf("expected the hold to be expired, but got %+v, %v", h, err)
+	}
+	if _, _, err = holds.Capture(ctx, h.ID, 0); !errors.Is(err, ErrHoldNotActive) {
+		t.Errorf("expected an expired hold not to be captured, but got %v", err)
+	}
+}
+
+func TestTransferStore_Reverse(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 1_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	store := TransferStore{db: db}
+	original := &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 300}
+	if err := store.CreateTransfer(ctx, original, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent partial refunds never give back more than was sent
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	refunded := int64(0)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reversal, err := store.Reverse(ctx, original.ID, 70, nil)
+			switch {
+			case err == nil:
+				mu.Lock()
+				refunded += reversal.Amount
+				mu.Unlock()
+			case !errors.Is(err, ErrReversalExceedsTransfer):
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if refunded != 280 {
+		t.Errorf("expected four refunds of 70, but %d was refunded", refunded)
+	}
+
+	got, err := store.Get(ctx, original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ReversedAmount != 280 || got.ReversalStatus != models.TransferReversalPartial {
+		t.Errorf("expected 280 to be reversed in part, but got %d %s", got.ReversedAmount, got.ReversalStatus)
+	}
+	reversal, err := store.Reverse(ctx, original.ID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reversal.Amount != 20 || reversal.FromAccountID != b.ID || reversal.ToAccountID != a.ID {
+		t.Errorf("expected the remaining 20 to go back from the payee, but got %+v", reversal)
+	}
+	if _, err = store.Reverse(ctx, original.ID, 0, nil); !errors.Is(err, ErrTransferReversed) {
+		t.Errorf("expected a reversed transfer not to be reversed again, but got %v", err)
+	}
+	if _, err = store.Reverse(ctx, reversal.ID, 0, nil); !errors.Is(err, ErrTransferIsReversal) {
+		t.Errorf("expected a reversal not to be reversed, but got %v", err)
+	}
+	got, err = store.Get(ctx, original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ReversalStatus != models.TransferReversalFull {
+		t.Errorf("expected the transfer to be reversed, but got %s", got.ReversalStatus)
+	}
+	assertConserved(t, db, accounts, 2_000)
+}
+
+func TestTransferStore_Reverse_cross_currency(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 2, 1_000)
+	a, b := accounts[0], accounts[1]
+	ctx := context.Background()
+	if _, err := db.Exec("UPDATE accounts SET currency = 'EUR' WHERE id = $1", b.ID); err != nil {
+		t.Fatal(err)
+	}
+	rates := exchange.NewMemoryProvider()
+	if err := rates.Set("USD", "EUR", "0.9"); err != nil {
+		t.Fatal(err)
+	}
+	store := TransferStore{db: db, rates: rates}
+	original := &models.Transfer{FromAccountID: a.ID, ToAccountID: b.ID, Amount: 1_000}
+	if err := store.CreateTransfer(ctx, original, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rate moving afterwards must not change what the payer gets back
+	if err := rates.Set("EUR", "USD", "2"); err != nil {
+		t.Fatal(err)
+	}
+	partial, err := store.Reverse(ctx, original.ID, 300, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.ToAmount != 333 || partial.ToCurrency != "USD" {
+		t.Errorf("expected 300 EUR to give back 333 USD, but got %d %s", partial.ToAmount, partial.ToCurrency)
+	}
+	rest, err := store.Reverse(ctx, original.ID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Amount != 600 || rest.ToAmount != 667 {
+		t.Errorf("expected the remaining 600 EUR to give back the remaining 667 USD, but got %d and %d", rest.Amount, rest.ToAmount)
+	}
+	acc, err := AccountStore{db: db}.Get(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Balance != 1_000 {
+		t.Errorf("expected the payer to be back at 1000, but got %d", acc.Balance)
+	}
+}
+
+func TestAccountStore_Adjust_frozen(t *testing.T) {
+	db := newTestDB(t)
+	accounts := newTestAccounts(t, db, 1, 1_000)
+	ctx := context.Background()
+	store := AccountStore{db: db}
+	if _, err := store.Freeze(ctx, accounts[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := store.Adjust(ctx, &models.Entry{AccountID: accounts[0].ID, Amount: -100, Reason: models.EntryReasonWithdrawal})
+	if !errors.Is(err, ErrAccountFrozen) {
+		t.Errorf("expected a withdrawal from a frozen account to be rejected, but got %v", err)
+	}
+	_, err = store.Adjust(ctx, &models.Entry{AccountID: accounts[0].ID, Amount: 100, Reason: models.EntryReasonDeposit})
+	if err != nil {
+		t.Errorf("expected a deposit into a frozen account to go through, but got %v", err)
+	}
+}
+
+func TestTransferStore_convert(t *testing.T) {
+	rates := exchange.NewMemoryProvider()
+	if err := rates.Set("JPY", "USD", "0.0067"); err != nil {
+		t.Fatal(err)
+	}
+	store := TransferStore{rates: rates}
+
+	tr := &models.Transfer{Amount: 1_000}
+	if err := store.convert(tr, "JPY", "USD"); err != nil || tr.ToAmount != 7 {
+		t.Errorf("expected 1000 JPY to credit 7 USD cents, but got %d, %v", tr.ToAmount, err)
+	}
+	tr = &models.Transfer{Amount: 50}
+	if err := store.convert(tr, "JPY", "USD"); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected an amount rounding down to nothing to be rejected, but got %d, %v", tr.ToAmount, err)
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Ruthvik10/simple_bank/internal/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateEmail = errors.New("duplicate email")
+)
+
+type UserStore struct {
+	db       *sqlx.DB
+	timeouts Timeouts
+}
+
+func (store UserStore) Get(ctx context.Context, id int64) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var u models.User
+	err := store.db.GetContext(ctx, &u, "SELECT * FROM users WHERE id = $1", id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &u, nil
+}
+
+func (store UserStore) Insert(ctx context.Context, u *models.User) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	if u.Role == "" {
+		u.Role = models.RoleCustomer
+	}
+	query := `INSERT INTO users (name, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING *`
+	err := store.db.QueryRowxContext(ctx, query, u.Name, u.Email, u.PasswordHash, u.Role).StructScan(u)
+	if err != nil {
+		var pqErr *pq.Error
+		switch {
+		case errors.As(err, &pqErr) && pqErr.Constraint == "users_email_key":
+			return ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (store UserStore) UpdateRole(ctx context.Context, u *models.User) error {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Write)
+	defer cancel()
+
+	query := `UPDATE users SET role = $1 WHERE id = $2 RETURNING *`
+	err := store.db.QueryRowxContext(ctx, query, u.Role, u.ID).StructScan(u)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (store UserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	var u models.User
+	err := store.db.GetContext(ctx, &u, "SELECT * FROM users WHERE lower(email) = lower($1)", email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &u, nil
+}
+
+// GetForToken returns the user holding the unexpired token with the given scope.
+func (store UserStore) GetForToken(ctx context.Context, scope, plaintext string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, store.timeouts.Read)
+	defer cancel()
+
+	query := `
+		SELECT users.* FROM users
+		INNER JOIN tokens ON users.id = tokens.user_id
+		WHERE tokens.hash = $1 AND tokens.scope = $2 AND tokens.expiry > $3`
+	var u models.User
+	err := store.db.GetContext(ctx, &u, query, models.HashToken(plaintext), scope, time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &u, nil
+}
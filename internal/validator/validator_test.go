@@ -0,0 +1,46 @@
+package validator
+
+import "testing"
+
+func TestValidator_Check(t *testing.T) {
+	v := New()
+	v.Check(true, "owner", "must be provided")
+	if !v.Valid() {
+		t.Fatalf("expected no errors, but got %v", v.Errors)
+	}
+	v.Check(false, "amount", "must be greater than zero")
+	v.Check(false, "amount", "must not be more than 10 million")
+	if v.Valid() {
+		t.Fatal("expected the validator to hold errors")
+	}
+	if v.Errors["amount"] != "must be greater than zero" {
+		t.Errorf("expected the first error of a field to be kept, but got %q", v.Errors["amount"])
+	}
+}
+
+func TestPermittedValue(t *testing.T) {
+	if !PermittedValue("EUR", "USD", "EUR") {
+		t.Error("expected EUR to be permitted")
+	}
+	if PermittedValue("eur", "USD", "EUR") {
+		t.Error("expected values to be compared case sensitively")
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		email string
+		valid bool
+	}{
+		{"ruthvik@example.com", true},
+		{"ruthvik.k+bank@mail.example.co", true},
+		{"ruthvik", false},
+		{"ruthvik@", false},
+		{"@example.com", false},
+	}
+	for _, e := range tests {
+		if got := Matches(e.email, EmailRX); got != e.valid {
+			t.Errorf("Matches(%q) = %t, expected %t", e.email, got, e.valid)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+CREATE TABLE "idempotency_keys" (
+  "key" varchar(255) PRIMARY KEY,
+  "request_hash" varchar NOT NULL,
+  "response_status" int NOT NULL,
+  "response_body" bytea NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "idempotency_keys"."request_hash" IS 'sha256 of the request payload the key was first used with';
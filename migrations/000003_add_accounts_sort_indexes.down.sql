@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS accounts_balance_id_idx;
+DROP INDEX IF EXISTS accounts_created_at_id_idx;
\ No newline at end of file
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS accounts_balance_id_idx ON "accounts" ("balance", "id");
+
+CREATE INDEX IF NOT EXISTS accounts_created_at_id_idx ON "accounts" ("created_at", "id");
@@ -0,0 +1,5 @@
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "exchange_rate";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_amount";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "to_currency";
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "currency";
+COMMENT ON COLUMN "transfers"."amount" IS 'amount cannot be negative';
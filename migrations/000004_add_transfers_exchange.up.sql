@@ -0,0 +1,21 @@
+ALTER TABLE "transfers" ADD COLUMN "currency" varchar;
+ALTER TABLE "transfers" ADD COLUMN "to_currency" varchar;
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+ALTER TABLE "transfers" ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+UPDATE "transfers" t SET
+  "currency" = fa."currency",
+  "to_currency" = ta."currency",
+  "to_amount" = t."amount"
+FROM "accounts" fa, "accounts" ta
+WHERE fa."id" = t."from_account_id" AND ta."id" = t."to_account_id";
+
+ALTER TABLE "transfers" ALTER COLUMN "currency" SET NOT NULL;
+ALTER TABLE "transfers" ALTER COLUMN "to_currency" SET NOT NULL;
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'amount debited from the payer in currency, cannot be negative';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited to the payee in to_currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of to_currency bought by one unit of currency';
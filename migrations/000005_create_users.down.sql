@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "user_id";
+DROP TABLE IF EXISTS tokens;
+DROP TABLE IF EXISTS users;
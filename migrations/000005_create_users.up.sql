@@ -0,0 +1,30 @@
+CREATE TABLE "users" (
+  "id" bigserial PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "password_hash" bytea NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX users_email_key ON "users" (lower("email"));
+
+CREATE TABLE "tokens" (
+  "hash" bytea PRIMARY KEY,
+  "user_id" bigint NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "expiry" timestamptz NOT NULL,
+  "scope" varchar NOT NULL
+);
+
+ALTER TABLE "accounts" ADD COLUMN "user_id" bigint REFERENCES "users" ("id");
+
+-- accounts opened before users existed are parked on a user that can not log in
+INSERT INTO "users" ("name", "email", "password_hash")
+SELECT 'Unclaimed accounts', 'unclaimed@simple-bank.invalid', ''
+WHERE EXISTS (SELECT 1 FROM "accounts");
+
+UPDATE "accounts" SET "user_id" = (SELECT "id" FROM "users" WHERE "email" = 'unclaimed@simple-bank.invalid')
+WHERE "user_id" IS NULL;
+
+ALTER TABLE "accounts" ALTER COLUMN "user_id" SET NOT NULL;
+
+CREATE INDEX ON "accounts" ("user_id");
@@ -0,0 +1,3 @@
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "note";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "actor_id";
+ALTER TABLE "entries" DROP COLUMN IF EXISTS "reason";
@@ -0,0 +1,9 @@
+ALTER TABLE "entries" ADD COLUMN "reason" varchar NOT NULL DEFAULT 'transfer';
+ALTER TABLE "entries" ADD COLUMN "actor_id" bigint REFERENCES "users" ("id");
+ALTER TABLE "entries" ADD COLUMN "note" varchar NOT NULL DEFAULT '';
+
+ALTER TABLE "entries" ALTER COLUMN "reason" DROP DEFAULT;
+
+COMMENT ON COLUMN "entries"."reason" IS 'why the balance moved: transfer, deposit, withdrawal, correction, fee or interest';
+
+COMMENT ON COLUMN "entries"."actor_id" IS 'user who booked the entry, null for entries booked by the system';
@@ -0,0 +1,44 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "user_id" bigint NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "schedule" varchar NOT NULL DEFAULT '',
+  "start_at" timestamptz NOT NULL,
+  "next_run_at" timestamptz,
+  "active" boolean NOT NULL DEFAULT true,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "scheduled_transfer_runs" (
+  "id" bigserial PRIMARY KEY,
+  "scheduled_transfer_id" bigint NOT NULL,
+  "due_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL,
+  "transfer_id" bigint,
+  "error" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("user_id");
+
+CREATE INDEX ON "scheduled_transfers" ("next_run_at") WHERE "active";
+
+CREATE UNIQUE INDEX ON "scheduled_transfer_runs" ("scheduled_transfer_id", "due_at");
+
+COMMENT ON COLUMN "scheduled_transfers"."schedule" IS 'recurrence rule, empty for a one-off transfer';
+
+COMMENT ON COLUMN "scheduled_transfers"."next_run_at" IS 'null once a one-off transfer has run';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("scheduled_transfer_id") REFERENCES "scheduled_transfers" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "scheduled_transfer_runs" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "scheduled_transfer_runs" ADD CONSTRAINT scheduled_transfer_runs_status_check CHECK ("status" IN ('succeeded', 'failed'));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "account_limits";
+
+DROP INDEX IF EXISTS "transfers_from_account_id_created_at_idx";
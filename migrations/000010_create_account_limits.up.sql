@@ -0,0 +1,19 @@
+CREATE TABLE "account_limits" (
+  "account_id" bigint PRIMARY KEY,
+  "max_transfer_amount" bigint,
+  "max_daily_amount" bigint,
+  "max_daily_count" integer,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
+
+COMMENT ON TABLE "account_limits" IS 'outgoing transfer limits, a missing row or a null limit is unlimited';
+
+COMMENT ON COLUMN "account_limits"."max_daily_amount" IS 'total sent per UTC day, in minor units of the account currency';
+
+ALTER TABLE "account_limits" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "account_limits" ADD CONSTRAINT account_limits_positive_check CHECK (
+  "max_transfer_amount" > 0 AND "max_daily_amount" > 0 AND "max_daily_count" > 0
+);
@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "overdrawn_since";
+
+ALTER TABLE "accounts" DROP COLUMN IF EXISTS "overdraft_limit";
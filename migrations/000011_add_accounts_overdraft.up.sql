@@ -0,0 +1,11 @@
+ALTER TABLE "accounts" ADD COLUMN "overdraft_limit" bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE "accounts" ADD COLUMN "overdrawn_since" timestamptz;
+
+ALTER TABLE "accounts" ADD CONSTRAINT accounts_overdraft_limit_check CHECK ("overdraft_limit" >= 0);
+
+COMMENT ON COLUMN "accounts"."overdraft_limit" IS 'how far below zero the balance may go, in minor units';
+
+COMMENT ON COLUMN "accounts"."overdrawn_since" IS 'start of the current stretch with a negative balance, null while the balance is not negative';
+
+UPDATE "accounts" SET "overdrawn_since" = now() WHERE "balance" < 0;
@@ -0,0 +1,33 @@
+CREATE TABLE "holds" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "user_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "captured_amount" bigint NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "holds" ("account_id");
+
+CREATE INDEX ON "holds" ("expires_at") WHERE "status" = 'active';
+
+COMMENT ON TABLE "holds" IS 'funds reserved on account_id until they are captured into a transfer to to_account_id, released or expired';
+
+COMMENT ON COLUMN "holds"."amount" IS 'amount reserved, in minor units of the account currency';
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "holds" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "holds" ADD CONSTRAINT holds_status_check CHECK ("status" IN ('active', 'captured', 'released', 'expired'));
+
+ALTER TABLE "holds" ADD CONSTRAINT holds_amount_check CHECK ("amount" > 0 AND "captured_amount" >= 0 AND "captured_amount" <= "amount");
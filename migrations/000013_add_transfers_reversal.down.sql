@@ -0,0 +1,3 @@
+COMMENT ON COLUMN "entries"."reason" IS 'why the balance moved: transfer, deposit, withdrawal, correction, fee or interest';
+
+ALTER TABLE "transfers" DROP COLUMN IF EXISTS "reversal_of";
@@ -0,0 +1,9 @@
+ALTER TABLE "transfers" ADD COLUMN "reversal_of" bigint;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("reversal_of") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "transfers" ("reversal_of");
+
+COMMENT ON COLUMN "transfers"."reversal_of" IS 'transfer this one reverses, fully or in part; null for ordinary transfers';
+
+COMMENT ON COLUMN "entries"."reason" IS 'why the balance moved: transfer, reversal, deposit, withdrawal, correction, fee or interest';
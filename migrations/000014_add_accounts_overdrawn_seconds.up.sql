@@ -0,0 +1,3 @@
+ALTER TABLE "accounts" ADD COLUMN "overdrawn_seconds" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "accounts"."overdrawn_seconds" IS 'total time spent overdrawn in the stretches that have ended, the current one started at overdrawn_since';